@@ -8,7 +8,11 @@ var (
 )
 
 func readFixture(filename string) string {
-	data, err := ioutil.ReadFile("testdata/" + filename + ".xml")
+	return readTestdata(filename + ".xml")
+}
+
+func readTestdata(filename string) string {
+	data, err := ioutil.ReadFile("testdata/" + filename)
 	if err != nil {
 		panic(err)
 	}
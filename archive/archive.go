@@ -0,0 +1,294 @@
+// Package archive reads and writes bookmarks, tags and notes in a stable
+// on-disk format. The same data is always written the same way, records
+// sorted and times in one canonical form, so archives kept in version control
+// produce small, readable diffs.
+//
+// Archives are either a single JSON Lines file, one record per line, or a
+// directory with a file per bookmark and per note.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// TimeLayout is the canonical form of times in archives. Times are written
+// in UTC to the second, the precision of the Pinboard API.
+const TimeLayout = "2006-01-02T15:04:05Z"
+
+// maxLine bounds the length of a record, which is mostly the description of a
+// bookmark or the text of a note.
+const maxLine = 16 << 20
+
+// Archive is the content of an account.
+type Archive struct {
+	Posts []*pin.Post
+	Tags  []*pin.Tag
+	Notes []*pin.Note
+}
+
+// Record types, written first in every record of a JSON Lines archive.
+const (
+	typePost = "post"
+	typeTag  = "tag"
+	typeNote = "note"
+)
+
+// The records fix the order and names of the fields written. Empty optional
+// fields are left out.
+type postRecord struct {
+	Type        string   `json:"type,omitempty"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Time        string   `json:"time,omitempty"`
+	Shared      bool     `json:"shared"`
+	ToRead      bool     `json:"toread"`
+	Hash        string   `json:"hash,omitempty"`
+	Author      string   `json:"author,omitempty"`
+}
+
+type tagRecord struct {
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type noteRecord struct {
+	Type      string `json:"type,omitempty"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Hash      string `json:"hash,omitempty"`
+	Length    int    `json:"length"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(TimeLayout)
+}
+
+func parseTime(field, s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(TimeLayout, s)
+	if err != nil {
+		return nil, &pin.FieldError{Field: field, Value: s, Err: err}
+	}
+	return &t, nil
+}
+
+func newPostRecord(p *pin.Post) *postRecord {
+	return &postRecord{
+		URL:         p.URL,
+		Title:       p.Title,
+		Description: p.Description,
+		Tags:        p.Tags,
+		Time:        formatTime(p.Time),
+		Shared:      p.Shared,
+		ToRead:      p.ToRead,
+		Hash:        p.Hash,
+		Author:      p.Author,
+	}
+}
+
+func (r *postRecord) post() (*pin.Post, error) {
+	t, err := parseTime("time", r.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &pin.Post{
+		URL:         r.URL,
+		Title:       r.Title,
+		Description: r.Description,
+		Tags:        r.Tags,
+		Time:        t,
+		Shared:      r.Shared,
+		ToRead:      r.ToRead,
+		Hash:        r.Hash,
+		Author:      r.Author,
+	}, nil
+}
+
+func newNoteRecord(n *pin.Note) *noteRecord {
+	return &noteRecord{
+		ID:        n.ID,
+		Title:     n.Title,
+		Hash:      n.Hash,
+		Length:    n.Length,
+		CreatedAt: formatTime(n.CreatedAt),
+		UpdatedAt: formatTime(n.UpdatedAt),
+		Text:      n.Text,
+	}
+}
+
+func (r *noteRecord) note() (*pin.Note, error) {
+	created, err := parseTime("created_at", r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := parseTime("updated_at", r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &pin.Note{
+		ID:        r.ID,
+		Title:     r.Title,
+		Hash:      r.Hash,
+		Length:    r.Length,
+		CreatedAt: created,
+		UpdatedAt: updated,
+		Text:      r.Text,
+	}, nil
+}
+
+// sorted returns copies of the slices of a in archive order: posts by URL,
+// tags by name and notes by ID. Ties are broken by the remaining fields so
+// the order never depends on the input.
+func (a *Archive) sorted() *Archive {
+	s := &Archive{
+		Posts: append([]*pin.Post(nil), a.Posts...),
+		Tags:  append([]*pin.Tag(nil), a.Tags...),
+		Notes: append([]*pin.Note(nil), a.Notes...),
+	}
+	sort.SliceStable(s.Posts, func(i, j int) bool {
+		a, b := s.Posts[i], s.Posts[j]
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		if ta, tb := formatTime(a.Time), formatTime(b.Time); ta != tb {
+			return ta < tb
+		}
+		return a.Title < b.Title
+	})
+	sort.SliceStable(s.Tags, func(i, j int) bool {
+		if s.Tags[i].Name != s.Tags[j].Name {
+			return s.Tags[i].Name < s.Tags[j].Name
+		}
+		return s.Tags[i].Count < s.Tags[j].Count
+	})
+	sort.SliceStable(s.Notes, func(i, j int) bool { return s.Notes[i].ID < s.Notes[j].ID })
+	return s
+}
+
+// marshal encodes v as one line of JSON, without escaping HTML characters,
+// which are common in titles and would only make diffs harder to read.
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Write writes a to w as JSON Lines: the posts, then the tags, then the
+// notes, each record on a line of its own.
+func Write(w io.Writer, a *Archive) error {
+	s := a.sorted()
+	bw := bufio.NewWriter(w)
+	write := func(v interface{}) error {
+		line, err := marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = bw.Write(line)
+		return err
+	}
+
+	for _, p := range s.Posts {
+		r := newPostRecord(p)
+		r.Type = typePost
+		if err := write(r); err != nil {
+			return err
+		}
+	}
+	for _, t := range s.Tags {
+		if err := write(&tagRecord{typeTag, t.Name, t.Count}); err != nil {
+			return err
+		}
+	}
+	for _, n := range s.Notes {
+		r := newNoteRecord(n)
+		r.Type = typeNote
+		if err := write(r); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Read reads an archive written by Write. Blank lines are ignored.
+func Read(r io.Reader) (*Archive, error) {
+	a := &Archive{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxLine)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := a.decode(line); err != nil {
+			return nil, fmt.Errorf("archive: line %d: %v", n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("archive: %v", err)
+	}
+	return a, nil
+}
+
+func (a *Archive) decode(line []byte) error {
+	var kind struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(line, &kind); err != nil {
+		return err
+	}
+
+	switch kind.Type {
+	case typePost:
+		var r postRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		p, err := r.post()
+		if err != nil {
+			return err
+		}
+		a.Posts = append(a.Posts, p)
+	case typeTag:
+		var r tagRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		a.Tags = append(a.Tags, &pin.Tag{Name: r.Name, Count: r.Count})
+	case typeNote:
+		var r noteRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		n, err := r.note()
+		if err != nil {
+			return err
+		}
+		a.Notes = append(a.Notes, n)
+	default:
+		return fmt.Errorf("unknown record type %q", kind.Type)
+	}
+	return nil
+}
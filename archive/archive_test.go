@@ -0,0 +1,151 @@
+package archive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func timeAt(t *testing.T, s string) *time.Time {
+	tm, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tm
+}
+
+func sample(t *testing.T) *Archive {
+	return &Archive{
+		Posts: []*pin.Post{
+			{URL: "https://b.com/x", Title: "B <b>", Tags: []string{"z", "a"}, Time: timeAt(t, "2020-01-02T04:05:06+02:00"), Shared: true},
+			{URL: "https://a.com/", Title: "A", Description: "line one\nline two", ToRead: true, Hash: "abc"},
+		},
+		Tags: []*pin.Tag{{Name: "z", Count: 1}, {Name: "a", Count: 2}},
+		Notes: []*pin.Note{
+			{ID: "n2", Title: "Two", Text: "second", Length: 6, CreatedAt: timeAt(t, "2020-01-01T00:00:00Z")},
+			{ID: "n1", Title: "One"},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	a := sample(t)
+	var buf bytes.Buffer
+	if err := Write(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"type":"post","url":"https://a.com/","title":"A","description":"line one\nline two","shared":false,"toread":true,"hash":"abc"}
+{"type":"post","url":"https://b.com/x","title":"B <b>","tags":["z","a"],"time":"2020-01-02T02:05:06Z","shared":true,"toread":false}
+{"type":"tag","name":"a","count":2}
+{"type":"tag","name":"z","count":1}
+{"type":"note","id":"n1","title":"One","length":0}
+{"type":"note","id":"n2","title":"Two","length":6,"created_at":"2020-01-01T00:00:00Z","text":"second"}
+`
+	if buf.String() != expected {
+		t.Errorf("Wrong output:\n%s", buf.String())
+	}
+	if a.Posts[0].URL != "https://b.com/x" {
+		t.Error("Write reordered its input")
+	}
+
+	// Writing the same content in any order gives the same bytes.
+	a.Posts[0], a.Posts[1] = a.Posts[1], a.Posts[0]
+	var again bytes.Buffer
+	if err := Write(&again, a); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != expected {
+		t.Error("Output depends on input order")
+	}
+}
+
+func TestRead(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sample(t)); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Posts) != 2 || len(a.Tags) != 2 || len(a.Notes) != 2 {
+		t.Fatalf("Wrong archive %+v", a)
+	}
+	p := a.Posts[1]
+	if p.URL != "https://b.com/x" || !p.Shared || !reflect.DeepEqual(p.Tags, []string{"z", "a"}) ||
+		!p.Time.Equal(*timeAt(t, "2020-01-02T02:05:06Z")) {
+		t.Errorf("Wrong post %+v", p)
+	}
+	if a.Posts[0].Time != nil || a.Posts[0].Description != "line one\nline two" {
+		t.Errorf("Wrong post %+v", a.Posts[0])
+	}
+	if n := a.Notes[1]; n.Text != "second" || n.CreatedAt == nil || n.UpdatedAt != nil {
+		t.Errorf("Wrong note %+v", n)
+	}
+
+	if _, err := Read(strings.NewReader(`{"type":"bogus"}`)); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected error for unknown type, got %v", err)
+	}
+	if _, err := Read(strings.NewReader("\n" + `{"type":"post","time":"yesterday"}`)); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error for bad time, got %v", err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := sample(t)
+	if err := WriteDir(dir, a); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, PostPath(a.Posts[0]))
+	if !strings.HasPrefix(PostPath(a.Posts[0]), filepath.Join("posts", "b.com")) {
+		t.Errorf("Wrong post path %s", PostPath(a.Posts[0]))
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\n  \"title\": \"B <b>\",\n") {
+		t.Errorf("Post file not one field per line:\n%s", data)
+	}
+
+	got, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, actual bytes.Buffer
+	Write(&expected, a)
+	Write(&actual, got)
+	if actual.String() != expected.String() {
+		t.Errorf("Round trip changed archive:\n%s", actual.String())
+	}
+
+	// Dropping a post removes its file and its empty host directory.
+	a.Posts = a.Posts[1:]
+	if err := WriteDir(dir, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed", filepath.Dir(path))
+	}
+	if got, err := ReadDir(dir); err != nil || len(got.Posts) != 1 {
+		t.Errorf("Expected 1 post got %+v, %v", got, err)
+	}
+
+	if empty, err := ReadDir(filepath.Join(dir, "missing")); err != nil || len(empty.Posts) != 0 {
+		t.Errorf("Expected empty archive got %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,244 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/internal/atomicfile"
+)
+
+// Layout of an archive directory. Posts are grouped by host so that a
+// listing of the directory is readable.
+const (
+	postsDir  = "posts"
+	notesDir  = "notes"
+	tagsFile  = "tags.jsonl"
+	recordExt = ".json"
+)
+
+// PostPath returns the path of the file holding p, relative to an archive
+// directory. It only depends on the URL of p, so an edited bookmark is
+// always written to the same file.
+func PostPath(p *pin.Post) string {
+	host := "_"
+	if u, err := url.Parse(p.URL); err == nil && u.Hostname() != "" {
+		host = cleanName(u.Hostname())
+	}
+	sum := sha256.Sum256([]byte(p.URL))
+	return filepath.Join(postsDir, host, hex.EncodeToString(sum[:8])+recordExt)
+}
+
+// NotePath returns the path of the file holding n, relative to an archive
+// directory.
+func NotePath(n *pin.Note) string {
+	return filepath.Join(notesDir, cleanName(n.ID)+recordExt)
+}
+
+// cleanName makes s safe to use as a file name.
+func cleanName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// indent encodes v as indented JSON, a field per line, without escaping HTML
+// characters.
+func indent(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteDir writes a to the directory dir, a file per post and per note and
+// the tags in one file. Files whose content has not changed are left alone,
+// and files of posts and notes no longer in a are removed, so the directory
+// always mirrors a exactly.
+func WriteDir(dir string, a *Archive) error {
+	s := a.sorted()
+	want := make(map[string][]byte)
+	for _, p := range s.Posts {
+		data, err := indent(newPostRecord(p))
+		if err != nil {
+			return err
+		}
+		path := PostPath(p)
+		if _, ok := want[path]; ok {
+			return fmt.Errorf("archive: duplicate post %s", p.URL)
+		}
+		want[path] = data
+	}
+	for _, n := range s.Notes {
+		data, err := indent(newNoteRecord(n))
+		if err != nil {
+			return err
+		}
+		path := NotePath(n)
+		if _, ok := want[path]; ok {
+			return fmt.Errorf("archive: duplicate note %s", n.ID)
+		}
+		want[path] = data
+	}
+	var tags bytes.Buffer
+	for _, t := range s.Tags {
+		line, err := marshal(&tagRecord{typeTag, t.Name, t.Count})
+		if err != nil {
+			return err
+		}
+		tags.Write(line)
+	}
+	want[tagsFile] = tags.Bytes()
+
+	for path, data := range want {
+		if err := writeIfChanged(filepath.Join(dir, path), data); err != nil {
+			return err
+		}
+	}
+
+	// Remove records that are no longer in the archive.
+	for _, sub := range []string{postsDir, notesDir} {
+		have, err := records(dir, sub)
+		if err != nil {
+			return err
+		}
+		for _, path := range have {
+			if _, ok := want[path]; ok {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, path)); err != nil {
+				return err
+			}
+			// Drop the host directory if this was its last post.
+			os.Remove(filepath.Dir(filepath.Join(dir, path)))
+		}
+	}
+	return nil
+}
+
+// ReadDir reads an archive written by WriteDir. A missing directory is an
+// empty archive. The records are returned in archive order.
+func ReadDir(dir string) (*Archive, error) {
+	a := &Archive{}
+
+	posts, err := records(dir, postsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range posts {
+		var r postRecord
+		if err := readRecord(dir, path, &r); err != nil {
+			return nil, err
+		}
+		p, err := r.post()
+		if err != nil {
+			return nil, fmt.Errorf("archive: %s: %v", path, err)
+		}
+		a.Posts = append(a.Posts, p)
+	}
+
+	notes, err := records(dir, notesDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range notes {
+		var r noteRecord
+		if err := readRecord(dir, path, &r); err != nil {
+			return nil, err
+		}
+		n, err := r.note()
+		if err != nil {
+			return nil, fmt.Errorf("archive: %s: %v", path, err)
+		}
+		a.Notes = append(a.Notes, n)
+	}
+
+	f, err := os.Open(filepath.Join(dir, tagsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		tags, err := Read(f)
+		if err != nil {
+			return nil, err
+		}
+		a.Tags = tags.Tags
+	}
+	return a.sorted(), nil
+}
+
+// records lists the record files under dir/sub, relative to dir, in lexical
+// order.
+func records(dir, sub string) ([]string, error) {
+	var paths []string
+	root := filepath.Join(dir, sub)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != recordExt {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func readRecord(dir, path string, v interface{}) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, path))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("archive: %s: %v", path, err)
+	}
+	return nil
+}
+
+// writeIfChanged writes data to path unless it already holds data, creating
+// parent directories as needed.
+func writeIfChanged(path string, data []byte) error {
+	if old, err := ioutil.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,198 @@
+// Package autotag adds tags to bookmarks by rule, such as tagging everything
+// from github.com with "code". Rules run as a stage of PostsService.Add for
+// new bookmarks, and as a reviewable batch job for existing ones.
+package autotag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// Rule adds Tags to the posts it matches. A post matches when it satisfies
+// every condition that is set; a rule without conditions matches nothing.
+type Rule struct {
+	Host  string   `json:"host"`  // the host or a parent domain, without "www."
+	Title string   `json:"title"` // a regexp searched for in the title
+	URL   string   `json:"url"`   // a regexp searched for in the URL
+	Tags  []string `json:"tags"`
+}
+
+type rule struct {
+	host       string
+	title, url *regexp.Regexp
+	tags       []string
+}
+
+func (r *rule) match(p *pin.Post) bool {
+	if r.host != "" {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			return false
+		}
+		h := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		if h != r.host && !strings.HasSuffix(h, "."+r.host) {
+			return false
+		}
+	}
+	if r.title != nil && !r.title.MatchString(p.Title) {
+		return false
+	}
+	if r.url != nil && !r.url.MatchString(p.URL) {
+		return false
+	}
+	return true
+}
+
+// Tagger applies a set of rules. It is safe for concurrent use.
+type Tagger struct {
+	rules []rule
+}
+
+// New returns a Tagger for rules.
+func New(rules []Rule) (*Tagger, error) {
+	t := &Tagger{}
+	for i, r := range rules {
+		if len(r.Tags) == 0 {
+			return nil, fmt.Errorf("autotag: rule %d has no tags", i+1)
+		}
+		if r.Host == "" && r.Title == "" && r.URL == "" {
+			return nil, fmt.Errorf("autotag: rule %d has no conditions", i+1)
+		}
+
+		cr := rule{
+			host: strings.TrimPrefix(strings.ToLower(r.Host), "www."),
+			tags: r.Tags,
+		}
+		var err error
+		if r.Title != "" {
+			if cr.title, err = regexp.Compile(r.Title); err != nil {
+				return nil, fmt.Errorf("autotag: rule %d: %v", i+1, err)
+			}
+		}
+		if r.URL != "" {
+			if cr.url, err = regexp.Compile(r.URL); err != nil {
+				return nil, fmt.Errorf("autotag: rule %d: %v", i+1, err)
+			}
+		}
+		t.rules = append(t.rules, cr)
+	}
+	return t, nil
+}
+
+// Load reads a JSON array of rules from the file at path and returns a
+// Tagger for them.
+func Load(path string) (*Tagger, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []Rule
+	if err := json.NewDecoder(f).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("autotag: %s: %v", path, err)
+	}
+	return New(rules)
+}
+
+// Tags returns the tags the rules give p that it does not have yet, compared
+// case-insensitively, in rule order.
+func (t *Tagger) Tags(p *pin.Post) []string {
+	have := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		have[strings.ToLower(tag)] = true
+	}
+
+	var add []string
+	for i := range t.rules {
+		if !t.rules[i].match(p) {
+			continue
+		}
+		for _, tag := range t.rules[i].tags {
+			if !have[strings.ToLower(tag)] {
+				have[strings.ToLower(tag)] = true
+				add = append(add, tag)
+			}
+		}
+	}
+	return add
+}
+
+// PreAdd adds the tags from the rules to p. Pass it to pin.WithPreAdd to tag
+// new bookmarks as they are saved.
+func (t *Tagger) PreAdd(p *pin.Post) error {
+	p.Tags = append(p.Tags, t.Tags(p)...)
+	return nil
+}
+
+// Change adds tags to one post.
+type Change struct {
+	Post *pin.Post
+	Add  []string
+}
+
+// Plan lists the changes the rules make to a set of posts. Review it, then
+// pass it to Apply.
+type Plan struct {
+	Changes []Change
+}
+
+// Plan works out the changes to posts, usually the output of
+// PostsService.All.
+func (t *Tagger) Plan(posts []*pin.Post) *Plan {
+	plan := &Plan{}
+	for _, p := range posts {
+		if add := t.Tags(p); len(add) > 0 {
+			plan.Changes = append(plan.Changes, Change{Post: p, Add: add})
+		}
+	}
+	return plan
+}
+
+// WriteTo writes a line per change to w.
+func (plan *Plan) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, c := range plan.Changes {
+		n, err := fmt.Fprintf(w, "%s +%s\n", c.Post.URL, strings.Join(c.Add, " +"))
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Apply saves the changes of plan, waiting interval between updates so as
+// not to hit the API rate limit. Posts keep their title, description, time
+// and flags. It stops at the first error or when ctx is done, and returns the
+// number of changes applied.
+func Apply(ctx context.Context, posts pin.PostsAPI, plan *Plan, interval time.Duration) (int, error) {
+	for i, c := range plan.Changes {
+		if i > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				return i, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+
+		p := *c.Post
+		p.Tags = append(append([]string(nil), c.Post.Tags...), c.Add...)
+		if _, err := pin.SavePost(posts, &p, true); err != nil {
+			return i, fmt.Errorf("autotag: saving %s: %v", p.URL, err)
+		}
+	}
+	return len(plan.Changes), nil
+}
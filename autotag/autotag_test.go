@@ -0,0 +1,94 @@
+package autotag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+var rules = []Rule{
+	{Host: "github.com", Tags: []string{"code"}},
+	{Title: `RFC \d+`, Tags: []string{"rfc", "standards"}},
+	{Host: "ietf.org", URL: `/rfc/`, Tags: []string{"Standards", "ietf"}},
+}
+
+func TestTags(t *testing.T) {
+	tagger, err := New(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		post *pin.Post
+		tags []string
+	}{
+		{&pin.Post{URL: "https://github.com/zachlatta/pin"}, []string{"code"}},
+		{&pin.Post{URL: "https://gist.github.com/x", Tags: []string{"Code"}}, nil},
+		{&pin.Post{URL: "https://notgithub.com/x"}, nil},
+		{&pin.Post{URL: "https://www.ietf.org/rfc/rfc2616.txt", Title: "RFC 2616"},
+			[]string{"rfc", "standards", "ietf"}},
+		{&pin.Post{URL: "https://www.ietf.org/about/", Title: "About"}, nil},
+	}
+	for _, tt := range tests {
+		if got := tagger.Tags(tt.post); !reflect.DeepEqual(got, tt.tags) {
+			t.Errorf("%s: expected %v got %v", tt.post.URL, tt.tags, got)
+		}
+	}
+
+	if _, err := New([]Rule{{Tags: []string{"x"}}}); err == nil {
+		t.Error("Expected error for rule without conditions")
+	}
+	if _, err := New([]Rule{{Title: "(", Tags: []string{"x"}}}); err == nil {
+		t.Error("Expected error for invalid regexp")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	tagger, err := New(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := []*pin.Post{
+		{URL: "https://github.com/a", Title: "A", Tags: []string{"go"}, Shared: true},
+		{URL: "https://example.com/b", Title: "B"},
+		{URL: "https://example.com/c", Title: "Notes on RFC 7231"},
+	}
+	plan := tagger.Plan(posts)
+
+	var buf bytes.Buffer
+	if _, err := plan.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "https://github.com/a +code\nhttps://example.com/c +rfc +standards\n"
+	if buf.String() != expected {
+		t.Errorf("Wrong plan:\n%s", buf.String())
+	}
+
+	fake := &pin.FakePostsService{}
+	n, err := Apply(context.Background(), fake, plan, time.Millisecond)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 changes applied got %d, %v", n, err)
+	}
+	adds := fake.AddCalls()
+	if !reflect.DeepEqual(adds[0].Tags, []string{"go", "code"}) || !adds[0].Shared || !adds[0].Replace {
+		t.Errorf("Wrong save %+v", adds[0])
+	}
+	if len(posts[0].Tags) != 1 {
+		t.Error("Original post was modified")
+	}
+
+	fake = &pin.FakePostsService{
+		AddStub: func(string, string, string, []string, *time.Time, bool, bool, bool) (*http.Response, error) {
+			return nil, errors.New("boom")
+		},
+	}
+	if n, err := Apply(context.Background(), fake, plan, 0); err == nil || n != 0 {
+		t.Errorf("Expected failure on first change got %d, %v", n, err)
+	}
+}
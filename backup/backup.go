@@ -0,0 +1,75 @@
+// Package backup takes snapshots of an account's posts, tags and notes,
+// keeps them in a directory under a retention policy, and restores
+// bookmarks from them.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// Snapshot is the state of an account at one time.
+type Snapshot struct {
+	Time  time.Time   `json:"time"`
+	Posts []*pin.Post `json:"posts"`
+	Tags  []*pin.Tag  `json:"tags"`
+	Notes []*pin.Note `json:"notes"`
+}
+
+// Source reads the state of an account. Tags and Notes may be nil to leave
+// them out of snapshots.
+type Source struct {
+	Posts pin.PostsAPI
+	Tags  pin.TagsAPI
+	Notes pin.NotesAPI
+
+	// Delay is the pause between fetching the text of each note, to stay
+	// within the API rate limit.
+	Delay time.Duration
+}
+
+// Take reads the posts, tags and notes of the account. Notes are fetched
+// one by one to include their text.
+func (s *Source) Take(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{Time: time.Now().UTC()}
+
+	var err error
+	if snap.Posts, _, err = s.Posts.All(nil, 0, 0, nil, nil); err != nil {
+		return nil, fmt.Errorf("backup: posts: %v", err)
+	}
+	if s.Tags != nil {
+		if snap.Tags, _, err = s.Tags.Get(); err != nil {
+			return nil, fmt.Errorf("backup: tags: %v", err)
+		}
+	}
+	if s.Notes == nil {
+		return snap, nil
+	}
+
+	list, _, err := s.Notes.List()
+	if err != nil {
+		return nil, fmt.Errorf("backup: notes: %v", err)
+	}
+	for i, n := range list {
+		if i > 0 && s.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(s.Delay):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		full, _, err := s.Notes.Get(n.ID)
+		if err != nil {
+			return nil, fmt.Errorf("backup: note %s: %v", n.ID, err)
+		}
+		snap.Notes = append(snap.Notes, full)
+	}
+	return snap, nil
+}
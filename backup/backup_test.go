@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestTake(t *testing.T) {
+	src := &Source{
+		Posts: &pin.FakePostsService{
+			AllStub: func([]string, int, int, *time.Time, *time.Time) ([]*pin.Post, *http.Response, error) {
+				return []*pin.Post{{URL: "https://example.com/"}}, nil, nil
+			},
+		},
+		Tags: &pin.FakeTagsService{
+			GetStub: func() ([]*pin.Tag, *http.Response, error) {
+				return []*pin.Tag{{Name: "go", Count: 1}}, nil, nil
+			},
+		},
+	}
+	notes := &pin.FakeNotesService{
+		ListStub: func() ([]*pin.Note, *http.Response, error) {
+			return []*pin.Note{{ID: "a"}, {ID: "b"}}, nil, nil
+		},
+		GetStub: func(id string) (*pin.Note, *http.Response, error) {
+			return &pin.Note{ID: id, Text: "text of " + id}, nil, nil
+		},
+	}
+	src.Notes = notes
+
+	snap, err := src.Take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Posts) != 1 || len(snap.Tags) != 1 || len(snap.Notes) != 2 {
+		t.Fatalf("Wrong snapshot %+v", snap)
+	}
+	if snap.Notes[1].Text != "text of b" {
+		t.Errorf("Note text not fetched %+v", snap.Notes[1])
+	}
+	if calls := notes.GetCalls(); !reflect.DeepEqual(calls, []string{"a", "b"}) {
+		t.Errorf("Wrong calls to Get %v", calls)
+	}
+}
+
+func snapshotAt(s string) *Snapshot {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &Snapshot{Time: t, Posts: []*pin.Post{{URL: "https://example.com/" + s}}}
+}
+
+func TestDir(t *testing.T) {
+	path, cleanup := tempDir(t)
+	defer cleanup()
+	d := &Dir{Path: path}
+
+	if _, err := d.Latest(); err == nil {
+		t.Error("Expected error for empty directory")
+	}
+
+	e, err := d.Save(snapshotAt("2020-01-01T10:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.File != "snapshot-20200101T100000Z.jsonl" || e.Posts != 1 || e.SHA256 == "" {
+		t.Errorf("Wrong entry %+v", e)
+	}
+	if _, err := d.Save(snapshotAt("2020-01-02T10:00:00Z")); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := d.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Posts[0].URL != "https://example.com/2020-01-02T10:00:00Z" {
+		t.Errorf("Wrong latest snapshot %+v", latest.Posts[0])
+	}
+
+	// Corrupt the first snapshot.
+	if err := ioutil.WriteFile(filepath.Join(path, e.File), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Load(e); err == nil {
+		t.Error("Expected checksum mismatch")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	path, cleanup := tempDir(t)
+	defer cleanup()
+	d := &Dir{Path: path}
+
+	times := []string{
+		"2020-01-15T10:00:00Z",
+		"2020-02-10T10:00:00Z",
+		"2020-02-11T09:00:00Z",
+		"2020-02-11T10:00:00Z",
+		"2020-02-12T09:00:00Z",
+		"2020-02-12T10:00:00Z",
+	}
+	for _, s := range times {
+		if _, err := d.Save(snapshotAt(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.Retention = Retention{KeepLast: 1, KeepDaily: 2, KeepMonthly: 2}
+	removed, err := d.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gone []string
+	for _, e := range removed {
+		gone = append(gone, e.Time.Format(time.RFC3339))
+		if _, err := os.Stat(filepath.Join(path, e.File)); !os.IsNotExist(err) {
+			t.Errorf("%s was not deleted", e.File)
+		}
+	}
+	expected := []string{"2020-02-10T10:00:00Z", "2020-02-11T09:00:00Z", "2020-02-12T09:00:00Z"}
+	if !reflect.DeepEqual(gone, expected) {
+		t.Errorf("Wrong snapshots removed %v", gone)
+	}
+
+	m, err := d.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Snapshots) != 3 || m.Snapshots[0].Time.Month() != time.January {
+		t.Errorf("Wrong manifest %+v", m.Snapshots)
+	}
+}
+
+func TestRunDefaultInterval(t *testing.T) {
+	path, cleanup := tempDir(t)
+	defer cleanup()
+	d := &Dir{Path: path}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	src := &Source{Posts: &pin.FakePostsService{
+		AllStub: func([]string, int, int, *time.Time, *time.Time) ([]*pin.Post, *http.Response, error) {
+			// Stop after the first snapshot.
+			cancel()
+			return []*pin.Post{{URL: "https://example.com/"}}, nil, nil
+		},
+	}}
+	if err := Run(ctx, src, d, 0, nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+
+	m, err := d.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Snapshots) != 1 {
+		t.Errorf("Expected 1 snapshot got %d", len(m.Snapshots))
+	}
+}
+
+func TestRestore(t *testing.T) {
+	snap := &Snapshot{Posts: []*pin.Post{
+		{URL: "https://b.com/", Title: "B", Tags: []string{"x"}, Shared: true},
+		{URL: "https://a.com/", Title: "A"},
+		{URL: "https://kept.com/"},
+	}}
+	live := []*pin.Post{{URL: "https://kept.com/"}, {URL: "https://extra.com/"}}
+
+	if plan := PlanRestore(snap, live, false); len(plan.Add) != 2 || len(plan.Delete) != 0 {
+		t.Errorf("Wrong plan without deletes %+v", plan)
+	}
+
+	plan := PlanRestore(snap, live, true)
+	var buf bytes.Buffer
+	if _, err := plan.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "add https://a.com/\nadd https://b.com/\ndelete https://extra.com/\n"
+	if buf.String() != expected {
+		t.Errorf("Wrong dry run:\n%s", buf.String())
+	}
+
+	fake := &pin.FakePostsService{}
+	n, err := Restore(context.Background(), fake, plan, time.Millisecond)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 changes got %d, %v", n, err)
+	}
+	adds := fake.AddCalls()
+	if len(adds) != 2 || adds[1].URL != "https://b.com/" || adds[1].Replace || !adds[1].Shared ||
+		!reflect.DeepEqual(adds[1].Tags, []string{"x"}) {
+		t.Errorf("Wrong adds %+v", adds)
+	}
+	if dels := fake.DeleteCalls(); !reflect.DeepEqual(dels, []string{"https://extra.com/"}) {
+		t.Errorf("Wrong deletes %v", dels)
+	}
+}
@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/zachlatta/pin/archive"
+	"github.com/zachlatta/pin/internal/atomicfile"
+)
+
+// manifestName is the name of the manifest file in a backup directory.
+const manifestName = "manifest.json"
+
+// Entry describes a snapshot file in the manifest.
+type Entry struct {
+	File   string    `json:"file"` // relative to the directory
+	Time   time.Time `json:"time"`
+	Posts  int       `json:"posts"`
+	Tags   int       `json:"tags"`
+	Notes  int       `json:"notes"`
+	SHA256 string    `json:"sha256"`
+}
+
+// Manifest lists the snapshots in a directory, oldest first.
+type Manifest struct {
+	Snapshots []Entry `json:"snapshots"`
+}
+
+// Retention says which snapshots to keep: the KeepLast newest, and the
+// newest of each of the KeepDaily most recent days and KeepMonthly most
+// recent months that have snapshots. A snapshot kept by any rule is kept. The
+// zero Retention keeps every snapshot.
+type Retention struct {
+	KeepLast    int
+	KeepDaily   int
+	KeepMonthly int
+}
+
+func (r Retention) zero() bool {
+	return r.KeepLast <= 0 && r.KeepDaily <= 0 && r.KeepMonthly <= 0
+}
+
+// keep reports which of entries, ordered oldest first, to keep.
+func (r Retention) keep(entries []Entry) []bool {
+	keep := make([]bool, len(entries))
+	if r.zero() {
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep
+	}
+
+	days := make(map[string]bool)
+	months := make(map[string]bool)
+	for i := len(entries) - 1; i >= 0; i-- {
+		t := entries[i].Time.UTC()
+		if len(entries)-i <= r.KeepLast {
+			keep[i] = true
+		}
+		if d := t.Format("2006-01-02"); !days[d] && len(days) < r.KeepDaily {
+			days[d] = true
+			keep[i] = true
+		}
+		if m := t.Format("2006-01"); !months[m] && len(months) < r.KeepMonthly {
+			months[m] = true
+			keep[i] = true
+		}
+	}
+	return keep
+}
+
+// Dir is a directory of snapshot files with a manifest. A Dir must not be
+// used by more than one process at a time.
+type Dir struct {
+	Path      string
+	Retention Retention
+}
+
+// Manifest reads the manifest. A directory without one has no snapshots.
+func (d *Dir) Manifest() (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(d.Path, manifestName))
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("backup: manifest: %v", err)
+	}
+	return &m, nil
+}
+
+func (d *Dir) writeManifest(m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(d.Path, manifestName), data, 0644)
+}
+
+// Save writes snap to a file named after its time, in the format of
+// archive.Write, adds it to the manifest and prunes old snapshots according
+// to the retention policy.
+func (d *Dir) Save(snap *Snapshot) (Entry, error) {
+	if err := os.MkdirAll(d.Path, 0755); err != nil {
+		return Entry{}, err
+	}
+	var buf bytes.Buffer
+	a := &archive.Archive{Posts: snap.Posts, Tags: snap.Tags, Notes: snap.Notes}
+	if err := archive.Write(&buf, a); err != nil {
+		return Entry{}, err
+	}
+
+	data := buf.Bytes()
+	sum := sha256.Sum256(data)
+	e := Entry{
+		File:   "snapshot-" + snap.Time.UTC().Format("20060102T150405Z") + ".jsonl",
+		Time:   snap.Time.UTC(),
+		Posts:  len(snap.Posts),
+		Tags:   len(snap.Tags),
+		Notes:  len(snap.Notes),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	if err := atomicfile.WriteFile(filepath.Join(d.Path, e.File), data, 0644); err != nil {
+		return Entry{}, err
+	}
+
+	m, err := d.Manifest()
+	if err != nil {
+		return Entry{}, err
+	}
+	entries := m.Snapshots[:0]
+	for _, old := range m.Snapshots {
+		if old.File != e.File {
+			entries = append(entries, old)
+		}
+	}
+	m.Snapshots = append(entries, e)
+	sort.SliceStable(m.Snapshots, func(i, j int) bool {
+		return m.Snapshots[i].Time.Before(m.Snapshots[j].Time)
+	})
+	if err := d.writeManifest(m); err != nil {
+		return Entry{}, err
+	}
+
+	_, err = d.Prune()
+	return e, err
+}
+
+// Load reads the snapshot of e, checking it against its checksum.
+func (d *Dir) Load(e Entry) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(filepath.Join(d.Path, e.File))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != e.SHA256 {
+		return nil, fmt.Errorf("backup: %s: checksum mismatch", e.File)
+	}
+
+	a, err := archive.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("backup: %s: %v", e.File, err)
+	}
+	return &Snapshot{Time: e.Time, Posts: a.Posts, Tags: a.Tags, Notes: a.Notes}, nil
+}
+
+// Latest reads the newest snapshot.
+func (d *Dir) Latest() (*Snapshot, error) {
+	m, err := d.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Snapshots) == 0 {
+		return nil, errors.New("backup: no snapshots")
+	}
+	return d.Load(m.Snapshots[len(m.Snapshots)-1])
+}
+
+// Prune deletes the snapshots the retention policy does not keep and
+// returns their entries.
+func (d *Dir) Prune() ([]Entry, error) {
+	m, err := d.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := d.Retention.keep(m.Snapshots)
+	var kept, removed []Entry
+	for i, e := range m.Snapshots {
+		if keep[i] {
+			kept = append(kept, e)
+		} else {
+			removed = append(removed, e)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	// Update the manifest first, so that it never lists missing files.
+	m.Snapshots = kept
+	if err := d.writeManifest(m); err != nil {
+		return nil, err
+	}
+	for _, e := range removed {
+		if err := os.Remove(filepath.Join(d.Path, e.File)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// DefaultInterval is the time between snapshots used by Run when none is
+// set.
+const DefaultInterval = 24 * time.Hour
+
+// Run takes a snapshot from src and saves it to d immediately and then every
+// interval until ctx is done. An interval of zero or less means
+// DefaultInterval. Errors are passed to onError, if not nil, and do not stop
+// it.
+func Run(ctx context.Context, src *Source, d *Dir, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		snap, err := src.Take(ctx)
+		if err == nil {
+			_, err = d.Save(snap)
+		}
+		if err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
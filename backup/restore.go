@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// RestorePlan lists what restoring a snapshot changes in an account. Review
+// it, or print it for a dry run, then pass it to Restore. Notes cannot be
+// written through the API, so they are not restored.
+type RestorePlan struct {
+	Add    []*pin.Post // in the snapshot but not the account
+	Delete []*pin.Post // in the account but not the snapshot
+}
+
+// PlanRestore compares snap with live, the posts of the account as returned
+// by PostsService.All, matching posts by URL. Extra posts in the account are
+// only planned for deletion if deleteExtras is set.
+func PlanRestore(snap *Snapshot, live []*pin.Post, deleteExtras bool) *RestorePlan {
+	inLive := make(map[string]bool, len(live))
+	for _, p := range live {
+		inLive[p.URL] = true
+	}
+	inSnap := make(map[string]bool, len(snap.Posts))
+	for _, p := range snap.Posts {
+		inSnap[p.URL] = true
+	}
+
+	plan := &RestorePlan{}
+	for _, p := range snap.Posts {
+		if !inLive[p.URL] {
+			plan.Add = append(plan.Add, p)
+		}
+	}
+	if deleteExtras {
+		for _, p := range live {
+			if !inSnap[p.URL] {
+				plan.Delete = append(plan.Delete, p)
+			}
+		}
+	}
+	byURL := func(ps []*pin.Post) {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].URL < ps[j].URL })
+	}
+	byURL(plan.Add)
+	byURL(plan.Delete)
+	return plan
+}
+
+// WriteTo writes the plan to w, a line per change.
+func (plan *RestorePlan) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	write := func(op string, p *pin.Post) error {
+		n, err := fmt.Fprintf(w, "%s %s\n", op, p.URL)
+		total += int64(n)
+		return err
+	}
+	for _, p := range plan.Add {
+		if err := write("add", p); err != nil {
+			return total, err
+		}
+	}
+	for _, p := range plan.Delete {
+		if err := write("delete", p); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Restore carries out plan, adding posts with all their fields and then
+// deleting extras, waiting delay between requests. Existing bookmarks are
+// never replaced. It stops at the first error or when ctx is done and
+// returns the number of changes made.
+func Restore(ctx context.Context, posts pin.PostsAPI, plan *RestorePlan, delay time.Duration) (int, error) {
+	n := 0
+	wait := func() error {
+		if n > 0 && delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		return ctx.Err()
+	}
+
+	for _, p := range plan.Add {
+		if err := wait(); err != nil {
+			return n, err
+		}
+		if _, err := pin.SavePost(posts, p, false); err != nil {
+			return n, fmt.Errorf("backup: adding %s: %v", p.URL, err)
+		}
+		n++
+	}
+	for _, p := range plan.Delete {
+		if err := wait(); err != nil {
+			return n, err
+		}
+		if _, err := posts.Delete(p.URL); err != nil {
+			return n, fmt.Errorf("backup: deleting %s: %v", p.URL, err)
+		}
+		n++
+	}
+	return n, nil
+}
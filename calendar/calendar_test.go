@@ -0,0 +1,146 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func date(s string, count int) *pin.Date {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return &pin.Date{Date: &t, Count: count}
+}
+
+var dates = []*pin.Date{
+	date("2020-01-06", 4),
+	date("2020-01-08", 1),
+	date("2020-01-01", 0),
+}
+
+func TestHeatmap(t *testing.T) {
+	h := &Heatmap{From: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	if err := h.WriteSVG(&buf, dates); err != nil {
+		t.Fatal(err)
+	}
+
+	var svg struct {
+		Rects []struct {
+			Date  string `xml:"data-date,attr"`
+			Fill  string `xml:"fill,attr"`
+			Y     int    `xml:"y,attr"`
+			Title string `xml:"title"`
+		} `xml:"rect"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatalf("Invalid SVG: %v\n%s", err, buf.String())
+	}
+	if len(svg.Rects) != 8 {
+		t.Fatalf("Expected 8 days got %d", len(svg.Rects))
+	}
+
+	first, busiest, quiet := svg.Rects[0], svg.Rects[5], svg.Rects[7]
+	if first.Date != "2020-01-01" || first.Fill != DefaultColors[0] {
+		t.Errorf("Wrong first day %+v", first)
+	}
+	if busiest.Date != "2020-01-06" || busiest.Fill != DefaultColors[4] ||
+		busiest.Title != "Jan 6, 2020: 4 bookmarks" {
+		t.Errorf("Wrong busiest day %+v", busiest)
+	}
+	if quiet.Fill != DefaultColors[1] {
+		t.Errorf("Wrong shade for quiet day %+v", quiet)
+	}
+	// 2020-01-06 is a Monday, the second row.
+	if busiest.Y != first.Y-2*12 {
+		t.Errorf("Wrong row for Monday: %d vs %d", busiest.Y, first.Y)
+	}
+
+	h.To = h.From.AddDate(0, 0, -1)
+	if err := h.WriteSVG(&buf, dates); err == nil {
+		t.Error("Expected error for empty range")
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	fake := &pin.FakePostsService{
+		GetStub: func(tags []string, dt *time.Time, urlStr string) ([]*pin.Post, *http.Response, error) {
+			if dt.Day() == 6 {
+				return []*pin.Post{
+					{URL: "https://example.com/a", Title: "Semicolons; commas, and more"},
+					{URL: "https://example.com/" + strings.Repeat("long", 30)},
+				}, nil, nil
+			}
+			return []*pin.Post{{URL: "https://example.com/b", Title: "B"}}, nil, nil
+		},
+	}
+	e := &Exporter{Posts: fake, Stamp: time.Date(2020, time.February, 1, 12, 0, 0, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	if err := e.WriteICS(context.Background(), &buf, dates); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if calls := fake.GetCalls(); len(calls) != 2 || calls[0].CreationTime.Day() != 6 {
+		t.Errorf("Wrong calls to Get %+v", calls)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:20200106@pin\r\nDTSTAMP:20200201T120000Z\r\nDTSTART;VALUE=DATE:20200106\r\nDTEND;VALUE=DATE:20200107\r\nSUMMARY:2 bookmarks\r\n",
+		`DESCRIPTION:- Semicolons\; commas\, and more\n  https://example.com/a\n- h`,
+		"SUMMARY:1 bookmark\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output lacks %q:\n%s", want, out)
+		}
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > maxLine {
+			t.Errorf("Line longer than %d octets: %q", maxLine, l)
+		}
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 2 {
+		t.Error("Expected an event per day with bookmarks")
+	}
+
+	fake.GetStub = func([]string, *time.Time, string) ([]*pin.Post, *http.Response, error) {
+		return nil, nil, errors.New("boom")
+	}
+	buf.Reset()
+	if err := e.WriteICS(context.Background(), &buf, dates); err == nil || buf.Len() != 0 {
+		t.Errorf("Expected error and no output got %v, %d bytes", err, buf.Len())
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	var b bytes.Buffer
+	s := strings.Repeat("é", 50)
+	writeLine(&b, s)
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	var joined string
+	for i, l := range lines {
+		if len(l) > maxLine {
+			t.Errorf("Line %d is %d octets", i, len(l))
+		}
+		if i > 0 {
+			l = strings.TrimPrefix(l, " ")
+		}
+		joined += l
+	}
+	if joined != s {
+		t.Error("Folding split a UTF-8 sequence or lost text")
+	}
+}
@@ -0,0 +1,134 @@
+// Package calendar presents bookmarking activity by day: as an activity
+// heatmap in SVG, and as an iCalendar file with an event per day listing the
+// bookmarks saved that day.
+package calendar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// DefaultColors shade the heatmap cells from no bookmarks to the most.
+var DefaultColors = []string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+// Heatmap draws a grid with a column per week and a row per weekday, each
+// cell shaded by the number of bookmarks saved that day.
+type Heatmap struct {
+	// From and To bound the days drawn. If To is zero, the last day in the
+	// dates is used; if From is zero, the grid covers the 52 weeks up to To.
+	From, To time.Time
+
+	// Cell is the size of a cell in pixels and Gap the space between cells.
+	// They default to 10 and 2.
+	Cell, Gap int
+
+	// Colors shade the cells, the first for days without bookmarks. The
+	// remaining colors split the busiest day's count evenly. If nil,
+	// DefaultColors is used.
+	Colors []string
+}
+
+// labelSpace is the room left for month and weekday labels.
+const labelSpace = 28
+
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// WriteSVG draws the heatmap of dates, as returned by PostsService.Dates, to
+// w.
+func (h *Heatmap) WriteSVG(w io.Writer, dates []*pin.Date) error {
+	cell, gap, colors := h.Cell, h.Gap, h.Colors
+	if cell <= 0 {
+		cell = 10
+	}
+	if gap <= 0 {
+		gap = 2
+	}
+	if len(colors) < 2 {
+		colors = DefaultColors
+	}
+
+	counts := make(map[time.Time]int)
+	var last time.Time
+	for _, d := range dates {
+		if d.Date == nil {
+			continue
+		}
+		t := day(*d.Date)
+		counts[t] += d.Count
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	to := last
+	if !h.To.IsZero() {
+		to = day(h.To)
+	}
+	if to.IsZero() {
+		to = day(time.Now())
+	}
+	from := to.AddDate(0, 0, -7*52+1)
+	if !h.From.IsZero() {
+		from = day(h.From)
+	}
+	if from.After(to) {
+		return errors.New("calendar: heatmap starts after it ends")
+	}
+	max := 0
+	for t, n := range counts {
+		if !t.Before(from) && !t.After(to) && n > max {
+			max = n
+		}
+	}
+
+	// Columns start on Sunday, so the first one may begin before from.
+	start := from.AddDate(0, 0, -int(from.Weekday()))
+	weeks := int(to.Sub(start).Hours()/24)/7 + 1
+	step := cell + gap
+	width := labelSpace + weeks*step
+	height := labelSpace/2 + 7*step
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="9">`+"\n",
+		width, height)
+	for i, name := range []string{"Mon", "Wed", "Fri"} {
+		fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`+"\n", labelSpace/2+(2*i+1)*step+cell-1, name)
+	}
+
+	month := time.Month(0)
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		col := int(d.Sub(start).Hours()/24) / 7
+		x := labelSpace + col*step
+		if d.Weekday() == time.Sunday && d.Month() != month {
+			month = d.Month()
+			fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", x, labelSpace/2-4, d.Month().String()[:3])
+		}
+		if d.Before(from) {
+			continue
+		}
+
+		n := counts[d]
+		level := 0
+		if n > 0 {
+			level = (n*(len(colors)-1) + max - 1) / max
+		}
+		y := labelSpace/2 + int(d.Weekday())*step
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" data-date="%s" data-count="%d"><title>`,
+			x, y, cell, cell, colors[level], d.Format("2006-01-02"), n)
+		xml.EscapeText(&b, []byte(fmt.Sprintf("%s: %d bookmarks", d.Format("Jan 2, 2006"), n)))
+		b.WriteString("</title></rect>\n")
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := b.WriteTo(w)
+	return err
+}
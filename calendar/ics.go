@@ -0,0 +1,142 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// maxLine is the longest line allowed by RFC 5545, in octets, excluding the
+// line break.
+const maxLine = 75
+
+// Exporter writes iCalendar files with an all-day event for each day that
+// bookmarks were saved, describing those bookmarks.
+type Exporter struct {
+	// Posts is used to fetch the bookmarks of each day.
+	Posts pin.PostsAPI
+
+	// From and To, if not zero, bound the days exported.
+	From, To time.Time
+
+	// Delay is the pause between fetching days, to stay within the API rate
+	// limit.
+	Delay time.Duration
+
+	// Stamp is the DTSTAMP of the events. If zero, the current time is used.
+	Stamp time.Time
+}
+
+// WriteICS writes a calendar for dates, as returned by PostsService.Dates,
+// to w. The bookmarks of each day with any are fetched with
+// Posts.Get(nil, day, ""). It stops at the first error or when ctx is done;
+// nothing is written to w unless every day was fetched.
+func (e *Exporter) WriteICS(ctx context.Context, w io.Writer, dates []*pin.Date) error {
+	var days []time.Time
+	seen := make(map[time.Time]bool)
+	for _, d := range dates {
+		if d.Date == nil || d.Count == 0 {
+			continue
+		}
+		t := day(*d.Date)
+		if seen[t] || (!e.From.IsZero() && t.Before(day(e.From))) ||
+			(!e.To.IsZero() && t.After(day(e.To))) {
+			continue
+		}
+		seen[t] = true
+		days = append(days, t)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	stamp := e.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	var b bytes.Buffer
+	line := func(s string) { writeLine(&b, s) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//pin//calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	for i, d := range days {
+		if i > 0 && e.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(e.Delay):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dt := d
+		posts, _, err := e.Posts.Get(nil, &dt, "")
+		if err != nil {
+			return fmt.Errorf("calendar: posts of %s: %v", d.Format("2006-01-02"), err)
+		}
+
+		var desc []string
+		for _, p := range posts {
+			title := p.Title
+			if title == "" {
+				title = p.URL
+			}
+			desc = append(desc, "- "+title+"\n  "+p.URL)
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:" + d.Format("20060102") + "@pin")
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + d.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + d.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeText(summary(len(posts))))
+		line("DESCRIPTION:" + escapeText(strings.Join(desc, "\n")))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+func summary(n int) string {
+	if n == 1 {
+		return "1 bookmark"
+	}
+	return fmt.Sprintf("%d bookmarks", n)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeText escapes s for use as an iCalendar TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine writes s to b terminated by CRLF, folding it into lines of at
+// most maxLine octets without splitting UTF-8 sequences. Continuation lines
+// start with a space.
+func writeLine(b *bytes.Buffer, s string) {
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLine - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
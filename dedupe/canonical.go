@@ -0,0 +1,178 @@
+// Package dedupe finds bookmarks that point at the same page under different
+// URLs and merges them. Pinboard treats http://x.com/a?utm_source=y and
+// https://x.com/a/ as distinct bookmarks; a Canonicalizer maps both to the
+// same key.
+package dedupe
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams are the query parameters removed from every URL.
+// Names ending in an asterisk match any parameter with that prefix.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"igshid",
+	"_hsenc",
+	"_hsmi",
+	"ref_src",
+}
+
+// Rules controls how a URL is canonicalised. The zero Rules canonicalise as
+// much as possible.
+type Rules struct {
+	// KeepScheme treats http and https URLs as different.
+	KeepScheme bool
+
+	// KeepWWW treats www.example.com and example.com as different.
+	KeepWWW bool
+
+	// KeepTrailingSlash treats /a and /a/ as different.
+	KeepTrailingSlash bool
+
+	// KeepFragment keeps all fragments. Otherwise only fragments that look
+	// like client-side routes, starting with ! or /, are kept.
+	KeepFragment bool
+
+	// KeepQueryOrder keeps query parameters in their original order
+	// instead of sorting them.
+	KeepQueryOrder bool
+
+	// TrackingParams are removed in addition to DefaultTrackingParams.
+	TrackingParams []string
+
+	// KeepParams are never removed, even if they match a tracking
+	// parameter.
+	KeepParams []string
+}
+
+// Canonicalizer maps URLs to a canonical form, with rules that can differ by
+// host. The zero Canonicalizer applies the zero Rules everywhere.
+type Canonicalizer struct {
+	Default Rules
+
+	// Hosts overrides Default for particular hosts and their subdomains.
+	// Keys are lower case host names without "www.".
+	Hosts map[string]Rules
+}
+
+// rules returns the rules for host, preferring the most specific match.
+func (c *Canonicalizer) rules(host string) Rules {
+	for h := host; h != ""; {
+		if r, ok := c.Hosts[h]; ok {
+			return r
+		}
+		i := strings.IndexByte(h, '.')
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return c.Default
+}
+
+// Canonical returns the canonical form of rawurl. URLs with the same canonical
+// form are considered duplicates.
+func (c *Canonicalizer) Canonical(rawurl string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	r := c.rules(strings.TrimPrefix(host, "www."))
+	if !r.KeepWWW {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		// Hostname drops the brackets of IPv6 literals.
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+	if !r.KeepScheme && u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if !r.KeepTrailingSlash && len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+
+	u.RawQuery = canonicalQuery(u.RawQuery, r)
+
+	if !r.KeepFragment && !strings.HasPrefix(u.Fragment, "!") &&
+		!strings.HasPrefix(u.Fragment, "/") {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return u.String(), nil
+}
+
+func canonicalQuery(rawQuery string, r Rules) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			name = pair[:i]
+		}
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if isTracking(name, r) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	if !r.KeepQueryOrder {
+		sort.Strings(kept)
+	}
+	return strings.Join(kept, "&")
+}
+
+func isTracking(name string, r Rules) bool {
+	for _, k := range r.KeepParams {
+		if name == k {
+			return false
+		}
+	}
+	for _, lists := range [][]string{DefaultTrackingParams, r.TrackingParams} {
+		for _, p := range lists {
+			if strings.HasSuffix(p, "*") {
+				if strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+					return true
+				}
+			} else if name == p {
+				return true
+			}
+		}
+	}
+	return false
+}
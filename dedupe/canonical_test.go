@@ -0,0 +1,62 @@
+package dedupe
+
+import "testing"
+
+var canonicalTests = []struct {
+	in, out string
+}{
+	{"http://x.com/a?utm_source=y", "https://x.com/a"},
+	{"https://x.com/a/", "https://x.com/a"},
+	{"HTTP://WWW.X.COM:80/a", "https://x.com/a"},
+	{"https://x.com:443", "https://x.com/"},
+	{"https://x.com:8443/a", "https://x.com:8443/a"},
+	{"https://x.com/a?b=2&a=1&fbclid=z", "https://x.com/a?a=1&b=2"},
+	{"https://x.com/a#section", "https://x.com/a"},
+	{"https://x.com/#!/inbox", "https://x.com/#!/inbox"},
+	{"https://x.com/Path/Case", "https://x.com/Path/Case"},
+	{"http://[::1]:8080/x", "https://[::1]:8080/x"},
+	{"http://[2001:DB8::1]:80/", "https://[2001:db8::1]/"},
+}
+
+func TestCanonical(t *testing.T) {
+	var c Canonicalizer
+	for _, tt := range canonicalTests {
+		got, err := c.Canonical(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.out {
+			t.Errorf("%s: expected %s got %s", tt.in, tt.out, got)
+		}
+	}
+}
+
+func TestCanonicalPerHost(t *testing.T) {
+	c := &Canonicalizer{
+		Default: Rules{TrackingParams: []string{"ref"}},
+		Hosts: map[string]Rules{
+			"news.ycombinator.com": {KeepParams: []string{"id"}},
+			"example.org":          {KeepTrailingSlash: true, KeepFragment: true, KeepScheme: true},
+		},
+	}
+
+	tests := []struct {
+		in, out string
+	}{
+		{"https://x.com/a?ref=home", "https://x.com/a"},
+		{"https://news.ycombinator.com/item?id=1&ref=x", "https://news.ycombinator.com/item?id=1&ref=x"},
+		{"http://www.example.org/a/#top", "http://example.org/a/#top"},
+		{"http://docs.example.org/a/", "http://docs.example.org/a/"},
+	}
+	for _, tt := range tests {
+		got, err := c.Canonical(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.out {
+			t.Errorf("%s: expected %s got %s", tt.in, tt.out, got)
+		}
+	}
+}
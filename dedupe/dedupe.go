@@ -0,0 +1,139 @@
+package dedupe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zachlatta/pin"
+)
+
+// ErrEmptyGroup is returned when merging a Group without posts.
+var ErrEmptyGroup = errors.New("dedupe: empty group")
+
+// Group is a set of posts whose URLs share a canonical form.
+type Group struct {
+	Key   string
+	Posts []*pin.Post // oldest first
+}
+
+// Find groups posts by canonical URL and returns the groups with more than
+// one post, ordered by key. Posts whose URL cannot be parsed are ignored.
+func Find(c *Canonicalizer, posts []*pin.Post) []Group {
+	byKey := make(map[string][]*pin.Post)
+	for _, p := range posts {
+		key, err := c.Canonical(p.URL)
+		if err != nil {
+			continue
+		}
+		byKey[key] = append(byKey[key], p)
+	}
+
+	var groups []Group
+	for key, ps := range byKey {
+		if len(ps) < 2 {
+			continue
+		}
+		sort.SliceStable(ps, func(i, j int) bool { return olderThan(ps[i], ps[j]) })
+		groups = append(groups, Group{Key: key, Posts: ps})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+func olderThan(a, b *pin.Post) bool {
+	switch {
+	case a.Time == nil:
+		return false
+	case b.Time == nil:
+		return true
+	}
+	return a.Time.Before(*b.Time)
+}
+
+// WriteReport writes a plain text summary of groups to w.
+func WriteReport(w io.Writer, groups []Group) error {
+	for _, g := range groups {
+		if _, err := fmt.Fprintf(w, "%s (%d bookmarks)\n", g.Key, len(g.Posts)); err != nil {
+			return err
+		}
+		for _, p := range g.Posts {
+			var date string
+			if p.Time != nil {
+				date = p.Time.Format("2006-01-02")
+			}
+			if _, err := fmt.Fprintf(w, "  %s  %s  [%s]\n", date, p.URL,
+				strings.Join(p.Tags, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Merge combines the posts of g into one. The URL and title of the survivor
+// are kept: an https URL is preferred, then the oldest post. Tags and
+// distinct descriptions of all posts are combined, the earliest time is kept,
+// the post is to-read if any of them is, and shared only if all of them are.
+// remove lists the URLs of the other posts. Merging a group without posts
+// fails with ErrEmptyGroup.
+func Merge(g Group) (merged *pin.Post, remove []string, err error) {
+	if len(g.Posts) == 0 {
+		return nil, nil, ErrEmptyGroup
+	}
+	keep := g.Posts[0]
+	for _, p := range g.Posts {
+		if strings.HasPrefix(p.URL, "https:") && !strings.HasPrefix(keep.URL, "https:") {
+			keep = p
+		}
+	}
+
+	m := *keep
+	m.Tags = nil
+	m.Shared = true
+	seenTag := make(map[string]bool)
+	var descs []string
+	seenDesc := make(map[string]bool)
+	for _, p := range g.Posts {
+		for _, t := range p.Tags {
+			if !seenTag[strings.ToLower(t)] {
+				seenTag[strings.ToLower(t)] = true
+				m.Tags = append(m.Tags, t)
+			}
+		}
+		if d := strings.TrimSpace(p.Description); d != "" && !seenDesc[d] {
+			seenDesc[d] = true
+			descs = append(descs, d)
+		}
+		if p.Time != nil && (m.Time == nil || p.Time.Before(*m.Time)) {
+			m.Time = p.Time
+		}
+		m.ToRead = m.ToRead || p.ToRead
+		m.Shared = m.Shared && p.Shared
+		if p.URL != keep.URL {
+			remove = append(remove, p.URL)
+		}
+	}
+	m.Description = strings.Join(descs, "\n\n")
+	return &m, remove, nil
+}
+
+// ApplyMerge merges the posts of g and writes the result back: the survivor
+// is replaced with the merged post, then the others are deleted.
+func ApplyMerge(posts pin.PostsAPI, g Group) error {
+	merged, remove, err := Merge(g)
+	if err != nil {
+		return err
+	}
+	if _, err := pin.SavePost(posts, merged, true); err != nil {
+		return fmt.Errorf("dedupe: saving %s: %v", merged.URL, err)
+	}
+	for _, u := range remove {
+		if _, err := posts.Delete(u); err != nil {
+			return fmt.Errorf("dedupe: deleting %s: %v", u, err)
+		}
+	}
+	return nil
+}
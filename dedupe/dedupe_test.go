@@ -0,0 +1,93 @@
+package dedupe
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func date(day int) *time.Time {
+	t := time.Date(2020, time.January, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+var posts = []*pin.Post{
+	{URL: "http://x.com/a?utm_source=y", Title: "A", Description: "first", Tags: []string{"one"}, Time: date(1), Shared: true},
+	{URL: "https://x.com/a/", Title: "A (https)", Description: "second", Tags: []string{"One", "two"}, Time: date(3), Shared: false, ToRead: true},
+	{URL: "https://x.com/a#comments", Description: "first", Tags: []string{"three"}, Time: date(2), Shared: true},
+	{URL: "https://x.com/b", Time: date(4)},
+}
+
+func TestFind(t *testing.T) {
+	groups := Find(&Canonicalizer{}, posts)
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 group got %d", len(groups))
+	}
+	g := groups[0]
+	if g.Key != "https://x.com/a" || len(g.Posts) != 3 {
+		t.Fatalf("Wrong group %s with %d posts", g.Key, len(g.Posts))
+	}
+	if g.Posts[0] != posts[0] || g.Posts[1] != posts[2] || g.Posts[2] != posts[1] {
+		t.Error("Group not ordered oldest first")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, groups); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "https://x.com/a (3 bookmarks)\n") {
+		t.Errorf("Wrong report:\n%s", buf.String())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	g := Find(&Canonicalizer{}, posts)[0]
+	merged, remove, err := Merge(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest https post survives.
+	if merged.URL != "https://x.com/a#comments" {
+		t.Errorf("Wrong survivor %s", merged.URL)
+	}
+	if strings.Join(merged.Tags, " ") != "one three two" {
+		t.Errorf("Wrong tags %v", merged.Tags)
+	}
+	if merged.Description != "first\n\nsecond" {
+		t.Errorf("Wrong description %q", merged.Description)
+	}
+	if !merged.Time.Equal(*date(1)) || !merged.ToRead || merged.Shared {
+		t.Errorf("Wrong time or flags %+v", merged)
+	}
+	if len(remove) != 2 || remove[0] != posts[0].URL || remove[1] != posts[1].URL {
+		t.Errorf("Wrong posts to remove %v", remove)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if _, _, err := Merge(Group{Key: "https://x.com/"}); err != ErrEmptyGroup {
+		t.Errorf("Expected ErrEmptyGroup got %v", err)
+	}
+	if err := ApplyMerge(&pin.FakePostsService{}, Group{}); err != ErrEmptyGroup {
+		t.Errorf("Expected ErrEmptyGroup got %v", err)
+	}
+}
+
+func TestApplyMerge(t *testing.T) {
+	fake := &pin.FakePostsService{}
+	if err := ApplyMerge(fake, Find(&Canonicalizer{}, posts)[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	adds := fake.AddCalls()
+	if len(adds) != 1 || adds[0].URL != "https://x.com/a#comments" || !adds[0].Replace {
+		t.Errorf("Wrong adds %+v", adds)
+	}
+	if dels := fake.DeleteCalls(); len(dels) != 2 {
+		t.Errorf("Wrong deletes %v", dels)
+	}
+}
@@ -0,0 +1,183 @@
+package pin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxSnippet is the number of leading body bytes kept in errors for
+// debugging.
+const maxSnippet = 512
+
+var (
+	// ErrUnauthorized is returned when the API rejects the auth token.
+	ErrUnauthorized = errors.New(http.StatusText(http.StatusUnauthorized))
+
+	// ErrTooManyRequests is returned when the API rate limit was hit.
+	ErrTooManyRequests = errors.New(http.StatusText(http.StatusTooManyRequests))
+)
+
+// StatusError is returned when the API responds with a non-2xx status other
+// than 401 or 429, such as a 503 during maintenance.
+type StatusError struct {
+	Response *http.Response
+	Snippet  string // the start of the response body
+}
+
+func (e *StatusError) Error() string {
+	if e.Response.Request == nil {
+		return fmt.Sprintf("pin: unexpected status %d", e.Response.StatusCode)
+	}
+	return fmt.Sprintf("pin: %s: unexpected status %d", e.Response.Request.URL.Path,
+		e.Response.StatusCode)
+}
+
+// ContentTypeError is returned when the API responds with a body that is not
+// in the expected format, usually an HTML error page from a proxy.
+type ContentTypeError struct {
+	ContentType string
+	Snippet     string // the start of the response body
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("pin: unexpected content type %q", e.ContentType)
+}
+
+// DecodeError is returned when a response body cannot be decoded, for
+// example because it was truncated.
+type DecodeError struct {
+	Err     error
+	Snippet string // the start of the response body
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("pin: decoding response: %v", e.Err)
+}
+
+// FieldError is returned when a field in an otherwise well-formed response
+// holds a value that cannot be interpreted.
+type FieldError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("pin: invalid %s %q: %v", e.Field, e.Value, e.Err)
+	}
+	return fmt.Sprintf("pin: invalid %s %q", e.Field, e.Value)
+}
+
+// ResultError is returned when the API answers a write with a result code
+// other than "done", for example because a bookmark was not added.
+type ResultError struct {
+	Endpoint string
+	Code     string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("pin: %s: %s", e.Endpoint, e.Code)
+}
+
+// resultDone is the result code of a successful write.
+const resultDone = "done"
+
+// resultResp is the body of the API's answer to a write.
+type resultResp struct {
+	Code string `xml:"code,attr"`
+}
+
+// ResponseTooLargeError is returned when a response body is larger than the
+// limit configured for its endpoint.
+type ResponseTooLargeError struct {
+	Endpoint string
+	Limit    int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("pin: %s: response larger than %d bytes", e.Endpoint, e.Limit)
+}
+
+// limitedReader reads from r until more than limit bytes have been read, at
+// which point it fails with a ResponseTooLargeError. A limit of zero or less
+// means no limit.
+type limitedReader struct {
+	r         io.Reader
+	endpoint  string
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.limit <= 0 {
+		return l.r.Read(p)
+	}
+	if l.exceeded {
+		return 0, l.err()
+	}
+	if l.remaining <= 0 {
+		// The limit has been reached exactly; any further byte exceeds it.
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, l.err()
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func (l *limitedReader) err() error {
+	return &ResponseTooLargeError{Endpoint: l.endpoint, Limit: l.limit}
+}
+
+// snippetWriter keeps the first maxSnippet bytes written to it.
+type snippetWriter struct {
+	buf []byte
+}
+
+func (w *snippetWriter) Write(p []byte) (int, error) {
+	if n := maxSnippet - len(w.buf); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+func (w *snippetWriter) String() string {
+	return string(w.buf)
+}
+
+// readSnippet reads up to maxSnippet bytes from r.
+func readSnippet(r io.Reader) string {
+	var w snippetWriter
+	io.Copy(&w, io.LimitReader(r, maxSnippet))
+	return w.String()
+}
+
+// isXML reports whether contentType names an XML media type. A missing
+// content type is accepted, as not every server sets one.
+func isXML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "text/xml" || mt == "application/xml" || strings.HasSuffix(mt, "+xml")
+}
@@ -0,0 +1,111 @@
+package pin
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func respondWith(status int, contentType, body string) httpmock.Responder {
+	return func(*http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(status, body)
+		if contentType != "" {
+			resp.Header.Set("Content-Type", contentType)
+		}
+		return resp, nil
+	}
+}
+
+func TestDoStatusError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/update?auth_token=user%3Atoken",
+		respondWith(http.StatusServiceUnavailable, "text/plain", "down for maintenance"))
+
+	_, resp, err := client.Posts.LastTimeUpdated()
+	serr, ok := err.(*StatusError)
+	if !ok {
+		t.Fatalf("Expected *StatusError got %T (%v)", err, err)
+	}
+	if serr.Response.StatusCode != http.StatusServiceUnavailable || resp == nil {
+		t.Errorf("Wrong response in error %+v", serr.Response)
+	}
+	if serr.Snippet != "down for maintenance" {
+		t.Errorf("Wrong snippet got '%s'", serr.Snippet)
+	}
+	if strings.Contains(err.Error(), "token") {
+		t.Errorf("Auth token leaked into error: %v", err)
+	}
+}
+
+func TestDoContentTypeError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	page := "<html><body>" + strings.Repeat("maintenance ", 100) + "</body></html>"
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/tags/get?auth_token=user%3Atoken",
+		respondWith(http.StatusOK, "text/html; charset=utf-8", page))
+
+	_, _, err := client.Tags.Get()
+	cerr, ok := err.(*ContentTypeError)
+	if !ok {
+		t.Fatalf("Expected *ContentTypeError got %T (%v)", err, err)
+	}
+	if cerr.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Wrong content type got '%s'", cerr.ContentType)
+	}
+	if len(cerr.Snippet) != maxSnippet || !strings.HasPrefix(page, cerr.Snippet) {
+		t.Errorf("Snippet not bounded to the start of the body, got %d bytes", len(cerr.Snippet))
+	}
+}
+
+func TestDoDecodeError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	truncated := readFixture("posts_all")[:200]
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/all?auth_token=user%3Atoken",
+		respondWith(http.StatusOK, "text/xml; charset=utf-8", truncated))
+
+	_, _, err := client.Posts.All(nil, 0, 0, nil, nil)
+	derr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatalf("Expected *DecodeError got %T (%v)", err, err)
+	}
+	if derr.Snippet != truncated {
+		t.Errorf("Wrong snippet got '%s'", derr.Snippet)
+	}
+}
+
+var postFieldErrs = []struct {
+	post  string
+	field string
+}{
+	{`<post href="http://example.org" time="yesterday" />`, "time"},
+	{`<post href="http://example.org" />`, "time"},
+	{`<post href="http://example.org" time="2011-03-24T20:30:47Z" toread="maybe" />`, "toread"},
+}
+
+func TestPostsFieldError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for _, tt := range postFieldErrs {
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/recent?auth_token=user%3Atoken&count=15",
+			respondWith(http.StatusOK, "", "<posts>"+tt.post+"</posts>"))
+
+		_, _, err := client.Posts.Recent(nil, -1)
+		ferr, ok := err.(*FieldError)
+		if !ok {
+			t.Errorf("Expected *FieldError for %s got %T (%v)", tt.post, err, err)
+			continue
+		}
+		if ferr.Field != tt.field {
+			t.Errorf("Wrong field expected %s got %s", tt.field, ferr.Field)
+		}
+	}
+}
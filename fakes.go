@@ -0,0 +1,456 @@
+package pin
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// The fakes in this file implement the service interfaces without any HTTP.
+// Each records the arguments of every call and answers with the matching Stub
+// function if one is set, or zero values otherwise. They can be plugged into a
+// Client directly:
+//
+//	posts := &pin.FakePostsService{}
+//	client := &pin.Client{Posts: posts}
+//
+// All fakes are safe for concurrent use.
+
+// FakePostsService is a fake PostsAPI.
+type FakePostsService struct {
+	AddStub             func(urlStr, title, description string, tags []string, creationTime *time.Time, replace, shared, toread bool) (*http.Response, error)
+	DeleteStub          func(urlStr string) (*http.Response, error)
+	GetStub             func(tags []string, creationTime *time.Time, urlStr string) ([]*Post, *http.Response, error)
+	LastTimeUpdatedStub func() (*time.Time, *http.Response, error)
+	DatesStub           func(tags []string) ([]*Date, *http.Response, error)
+	RecentStub          func(tags []string, count int) ([]*Post, *http.Response, error)
+	AllStub             func(tags []string, start int, results int, fromdt, todt *time.Time) ([]*Post, *http.Response, error)
+	SuggestStub         func(urlStr string) ([]string, []string, *http.Response, error)
+
+	mu                   sync.Mutex
+	addCalls             []FakePostsAddCall
+	deleteCalls          []string
+	getCalls             []FakePostsGetCall
+	lastTimeUpdatedCalls int
+	datesCalls           [][]string
+	recentCalls          []FakePostsRecentCall
+	allCalls             []FakePostsAllCall
+	suggestCalls         []string
+}
+
+// FakePostsAddCall holds the arguments of a call to FakePostsService.Add.
+type FakePostsAddCall struct {
+	URL          string
+	Title        string
+	Description  string
+	Tags         []string
+	CreationTime *time.Time
+	Replace      bool
+	Shared       bool
+	ToRead       bool
+}
+
+// FakePostsGetCall holds the arguments of a call to FakePostsService.Get.
+type FakePostsGetCall struct {
+	Tags         []string
+	CreationTime *time.Time
+	URL          string
+}
+
+// FakePostsRecentCall holds the arguments of a call to FakePostsService.Recent.
+type FakePostsRecentCall struct {
+	Tags  []string
+	Count int
+}
+
+// FakePostsAllCall holds the arguments of a call to FakePostsService.All.
+type FakePostsAllCall struct {
+	Tags    []string
+	Start   int
+	Results int
+	FromDT  *time.Time
+	ToDT    *time.Time
+}
+
+func (f *FakePostsService) Add(urlStr, title, description string, tags []string,
+	creationTime *time.Time, replace, shared, toread bool) (*http.Response, error) {
+	f.mu.Lock()
+	f.addCalls = append(f.addCalls, FakePostsAddCall{urlStr, title, description,
+		copyStrings(tags), creationTime, replace, shared, toread})
+	stub := f.AddStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(urlStr, title, description, tags, creationTime, replace, shared, toread)
+	}
+	return nil, nil
+}
+
+// AddCalls returns the arguments of every call to Add so far.
+func (f *FakePostsService) AddCalls() []FakePostsAddCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePostsAddCall(nil), f.addCalls...)
+}
+
+func (f *FakePostsService) Delete(urlStr string) (*http.Response, error) {
+	f.mu.Lock()
+	f.deleteCalls = append(f.deleteCalls, urlStr)
+	stub := f.DeleteStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(urlStr)
+	}
+	return nil, nil
+}
+
+// DeleteCalls returns the URL passed to every call to Delete so far.
+func (f *FakePostsService) DeleteCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleteCalls...)
+}
+
+func (f *FakePostsService) Get(tags []string, creationTime *time.Time,
+	urlStr string) ([]*Post, *http.Response, error) {
+	f.mu.Lock()
+	f.getCalls = append(f.getCalls, FakePostsGetCall{copyStrings(tags), creationTime, urlStr})
+	stub := f.GetStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(tags, creationTime, urlStr)
+	}
+	return nil, nil, nil
+}
+
+// GetCalls returns the arguments of every call to Get so far.
+func (f *FakePostsService) GetCalls() []FakePostsGetCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePostsGetCall(nil), f.getCalls...)
+}
+
+func (f *FakePostsService) LastTimeUpdated() (*time.Time, *http.Response, error) {
+	f.mu.Lock()
+	f.lastTimeUpdatedCalls++
+	stub := f.LastTimeUpdatedStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	return nil, nil, nil
+}
+
+// LastTimeUpdatedCallCount returns the number of calls to LastTimeUpdated so
+// far.
+func (f *FakePostsService) LastTimeUpdatedCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lastTimeUpdatedCalls
+}
+
+func (f *FakePostsService) Dates(tags []string) ([]*Date, *http.Response, error) {
+	f.mu.Lock()
+	f.datesCalls = append(f.datesCalls, copyStrings(tags))
+	stub := f.DatesStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(tags)
+	}
+	return nil, nil, nil
+}
+
+// DatesCalls returns the tags passed to every call to Dates so far.
+func (f *FakePostsService) DatesCalls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.datesCalls...)
+}
+
+func (f *FakePostsService) Recent(tags []string, count int) ([]*Post, *http.Response, error) {
+	f.mu.Lock()
+	f.recentCalls = append(f.recentCalls, FakePostsRecentCall{copyStrings(tags), count})
+	stub := f.RecentStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(tags, count)
+	}
+	return nil, nil, nil
+}
+
+// RecentCalls returns the arguments of every call to Recent so far.
+func (f *FakePostsService) RecentCalls() []FakePostsRecentCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePostsRecentCall(nil), f.recentCalls...)
+}
+
+func (f *FakePostsService) All(tags []string, start int, results int,
+	fromdt, todt *time.Time) ([]*Post, *http.Response, error) {
+	f.mu.Lock()
+	f.allCalls = append(f.allCalls, FakePostsAllCall{copyStrings(tags), start, results, fromdt, todt})
+	stub := f.AllStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(tags, start, results, fromdt, todt)
+	}
+	return nil, nil, nil
+}
+
+// AllCalls returns the arguments of every call to All so far.
+func (f *FakePostsService) AllCalls() []FakePostsAllCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePostsAllCall(nil), f.allCalls...)
+}
+
+func (f *FakePostsService) Suggest(urlStr string) ([]string, []string, *http.Response, error) {
+	f.mu.Lock()
+	f.suggestCalls = append(f.suggestCalls, urlStr)
+	stub := f.SuggestStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(urlStr)
+	}
+	return nil, nil, nil, nil
+}
+
+// SuggestCalls returns the URL passed to every call to Suggest so far.
+func (f *FakePostsService) SuggestCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.suggestCalls...)
+}
+
+// FakeTagsService is a fake TagsAPI.
+type FakeTagsService struct {
+	GetStub    func() ([]*Tag, *http.Response, error)
+	DeleteStub func(tag string) (*http.Response, error)
+	RenameStub func(newTag, oldTag string) (*http.Response, error)
+
+	mu          sync.Mutex
+	getCalls    int
+	deleteCalls []string
+	renameCalls []FakeTagsRenameCall
+}
+
+// FakeTagsRenameCall holds the arguments of a call to FakeTagsService.Rename.
+type FakeTagsRenameCall struct {
+	NewTag string
+	OldTag string
+}
+
+func (f *FakeTagsService) Get() ([]*Tag, *http.Response, error) {
+	f.mu.Lock()
+	f.getCalls++
+	stub := f.GetStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	return nil, nil, nil
+}
+
+// GetCallCount returns the number of calls to Get so far.
+func (f *FakeTagsService) GetCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.getCalls
+}
+
+func (f *FakeTagsService) Delete(tag string) (*http.Response, error) {
+	f.mu.Lock()
+	f.deleteCalls = append(f.deleteCalls, tag)
+	stub := f.DeleteStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(tag)
+	}
+	return nil, nil
+}
+
+// DeleteCalls returns the tag passed to every call to Delete so far.
+func (f *FakeTagsService) DeleteCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleteCalls...)
+}
+
+func (f *FakeTagsService) Rename(newTag, oldTag string) (*http.Response, error) {
+	f.mu.Lock()
+	f.renameCalls = append(f.renameCalls, FakeTagsRenameCall{newTag, oldTag})
+	stub := f.RenameStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(newTag, oldTag)
+	}
+	return nil, nil
+}
+
+// RenameCalls returns the arguments of every call to Rename so far.
+func (f *FakeTagsService) RenameCalls() []FakeTagsRenameCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeTagsRenameCall(nil), f.renameCalls...)
+}
+
+// FakeUserService is a fake UserAPI.
+type FakeUserService struct {
+	SecretRSSKeyStub func() (string, *http.Response, error)
+	APITokenStub     func() (string, *http.Response, error)
+
+	mu                sync.Mutex
+	secretRSSKeyCalls int
+	apiTokenCalls     int
+}
+
+func (f *FakeUserService) SecretRSSKey() (string, *http.Response, error) {
+	f.mu.Lock()
+	f.secretRSSKeyCalls++
+	stub := f.SecretRSSKeyStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	return "", nil, nil
+}
+
+// SecretRSSKeyCallCount returns the number of calls to SecretRSSKey so far.
+func (f *FakeUserService) SecretRSSKeyCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.secretRSSKeyCalls
+}
+
+func (f *FakeUserService) APIToken() (string, *http.Response, error) {
+	f.mu.Lock()
+	f.apiTokenCalls++
+	stub := f.APITokenStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	return "", nil, nil
+}
+
+// APITokenCallCount returns the number of calls to APIToken so far.
+func (f *FakeUserService) APITokenCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.apiTokenCalls
+}
+
+// FakeNotesService is a fake NotesAPI.
+type FakeNotesService struct {
+	ListStub func() ([]*Note, *http.Response, error)
+	GetStub  func(id string) (*Note, *http.Response, error)
+
+	mu        sync.Mutex
+	listCalls int
+	getCalls  []string
+}
+
+func (f *FakeNotesService) List() ([]*Note, *http.Response, error) {
+	f.mu.Lock()
+	f.listCalls++
+	stub := f.ListStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	return nil, nil, nil
+}
+
+// ListCallCount returns the number of calls to List so far.
+func (f *FakeNotesService) ListCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.listCalls
+}
+
+func (f *FakeNotesService) Get(id string) (*Note, *http.Response, error) {
+	f.mu.Lock()
+	f.getCalls = append(f.getCalls, id)
+	stub := f.GetStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(id)
+	}
+	return nil, nil, nil
+}
+
+// GetCalls returns the id passed to every call to Get so far.
+func (f *FakeNotesService) GetCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return copyStrings(f.getCalls)
+}
+
+// FakeFeedsService is a fake FeedsAPI.
+type FakeFeedsService struct {
+	URLStub   func(feed Feed, format FeedFormat, count int) (*url.URL, error)
+	FetchStub func(feed Feed, format FeedFormat, count int) ([]*Post, *http.Response, error)
+
+	mu         sync.Mutex
+	urlCalls   []FakeFeedsCall
+	fetchCalls []FakeFeedsCall
+}
+
+// FakeFeedsCall holds the arguments of a call to FakeFeedsService.URL or
+// FakeFeedsService.Fetch.
+type FakeFeedsCall struct {
+	Feed   Feed
+	Format FeedFormat
+	Count  int
+}
+
+func (f *FakeFeedsService) URL(feed Feed, format FeedFormat, count int) (*url.URL, error) {
+	f.mu.Lock()
+	f.urlCalls = append(f.urlCalls, FakeFeedsCall{feed, format, count})
+	stub := f.URLStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(feed, format, count)
+	}
+	return nil, nil
+}
+
+// URLCalls returns the arguments of every call to URL so far.
+func (f *FakeFeedsService) URLCalls() []FakeFeedsCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeFeedsCall(nil), f.urlCalls...)
+}
+
+func (f *FakeFeedsService) Fetch(feed Feed, format FeedFormat, count int) ([]*Post, *http.Response, error) {
+	f.mu.Lock()
+	f.fetchCalls = append(f.fetchCalls, FakeFeedsCall{feed, format, count})
+	stub := f.FetchStub
+	f.mu.Unlock()
+	if stub != nil {
+		return stub(feed, format, count)
+	}
+	return nil, nil, nil
+}
+
+// FetchCalls returns the arguments of every call to Fetch so far.
+func (f *FakeFeedsService) FetchCalls() []FakeFeedsCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeFeedsCall(nil), f.fetchCalls...)
+}
+
+var (
+	_ PostsAPI = (*FakePostsService)(nil)
+	_ TagsAPI  = (*FakeTagsService)(nil)
+	_ UserAPI  = (*FakeUserService)(nil)
+	_ NotesAPI = (*FakeNotesService)(nil)
+	_ FeedsAPI = (*FakeFeedsService)(nil)
+)
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
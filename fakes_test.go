@@ -0,0 +1,58 @@
+package pin
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFakePostsService(t *testing.T) {
+	posts := &FakePostsService{
+		RecentStub: func(tags []string, count int) ([]*Post, *http.Response, error) {
+			return []*Post{{URL: "http://example.org"}}, nil, nil
+		},
+	}
+	c := &Client{Posts: posts}
+
+	tags := []string{"one"}
+	got, _, err := c.Posts.Recent(tags, 5)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(got) != 1 || got[0].URL != "http://example.org" {
+		t.Errorf("Stub result not returned, got %v", got)
+	}
+
+	tags[0] = "changed"
+	calls := posts.RecentCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Wrong number of calls expected 1 got %d", len(calls))
+	}
+	if calls[0].Count != 5 || calls[0].Tags[0] != "one" {
+		t.Errorf("Wrong call recorded %+v", calls[0])
+	}
+
+	if _, err := c.Posts.Delete("http://example.org"); err != nil {
+		t.Errorf("Unstubbed method returned %v", err)
+	}
+	if calls := posts.DeleteCalls(); len(calls) != 1 || calls[0] != "http://example.org" {
+		t.Errorf("Wrong delete calls recorded %v", calls)
+	}
+}
+
+func TestFakeTagsService(t *testing.T) {
+	errRename := errors.New("rename failed")
+	tags := &FakeTagsService{
+		RenameStub: func(newTag, oldTag string) (*http.Response, error) {
+			return nil, errRename
+		},
+	}
+
+	if _, err := tags.Rename("new", "old"); err != errRename {
+		t.Errorf("Expected stub error got %v", err)
+	}
+	calls := tags.RenameCalls()
+	if len(calls) != 1 || calls[0].NewTag != "new" || calls[0].OldTag != "old" {
+		t.Errorf("Wrong rename calls recorded %v", calls)
+	}
+}
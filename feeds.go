@@ -0,0 +1,267 @@
+package pin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultFeedsURL = "https://feeds.pinboard.in/"
+
+// feedsEndpoint is the name used to configure the response size limit of
+// feeds with WithEndpointMaxResponseSize.
+const feedsEndpoint = "feeds"
+
+// FeedsService is the service for reading the public and private RSS and JSON
+// feeds served from feeds.pinboard.in. Feeds need no auth token; private ones
+// are unlocked by the key returned from UserService.SecretRSSKey instead.
+type FeedsService struct {
+	client *Client
+}
+
+// FeedFormat selects the format a feed is requested in.
+type FeedFormat int
+
+const (
+	FeedJSON FeedFormat = iota
+	FeedRSS
+)
+
+func (f FeedFormat) String() string {
+	if f == FeedRSS {
+		return "rss"
+	}
+	return "json"
+}
+
+// Feed identifies one of the feeds published by Pinboard. Use the
+// constructors below rather than filling it in by hand.
+type Feed struct {
+	Secret   string   // the secret RSS key for private feeds
+	Username string   // the user whose bookmarks are listed
+	Tags     []string // up to 3 tags to filter by
+	Section  string   // "popular", "recent", "network" or "toread"
+}
+
+// UserFeed is the public feed of a user's bookmarks, optionally filtered by
+// up to 3 tags.
+func UserFeed(username string, tags ...string) Feed {
+	return Feed{Username: username, Tags: tags}
+}
+
+// PrivateFeed is a user's feed including private bookmarks.
+func PrivateFeed(secret, username string, tags ...string) Feed {
+	return Feed{Secret: secret, Username: username, Tags: tags}
+}
+
+// NetworkFeed lists bookmarks from the users in a user's network.
+func NetworkFeed(secret, username string) Feed {
+	return Feed{Secret: secret, Username: username, Section: "network"}
+}
+
+// ToReadFeed lists a user's unread bookmarks.
+func ToReadFeed(secret, username string) Feed {
+	return Feed{Secret: secret, Username: username, Section: "toread"}
+}
+
+// PopularFeed lists the bookmarks that are popular site-wide.
+func PopularFeed() Feed {
+	return Feed{Section: "popular"}
+}
+
+// RecentFeed lists the most recent public bookmarks site-wide.
+func RecentFeed() Feed {
+	return Feed{Section: "recent"}
+}
+
+// path returns the path of the feed relative to the feeds URL, without the
+// leading format segment.
+func (f Feed) path() (string, error) {
+	if len(f.Tags) > 3 {
+		return "", errors.New("too many tags (max is 3)")
+	}
+
+	var segs []string
+	if f.Secret != "" {
+		segs = append(segs, "secret:"+f.Secret)
+	}
+	if f.Username != "" {
+		segs = append(segs, "u:"+f.Username)
+	}
+	for _, t := range f.Tags {
+		segs = append(segs, "t:"+t)
+	}
+
+	switch f.Section {
+	case "":
+		if f.Username == "" {
+			return "", errors.New("feed needs a username")
+		}
+	case "network", "toread":
+		if f.Secret == "" || f.Username == "" {
+			return "", errors.New(f.Section + " feed needs a secret and username")
+		}
+		segs = append(segs, f.Section)
+	case "popular", "recent":
+		segs = append(segs, f.Section)
+	default:
+		return "", errors.New("unknown feed section " + f.Section)
+	}
+
+	return strings.Join(segs, "/") + "/", nil
+}
+
+// URL returns the address of feed in the given format. If count is positive,
+// it limits the number of bookmarks returned.
+//
+// https://pinboard.in/howto/#rss
+func (s *FeedsService) URL(feed Feed, format FeedFormat, count int) (*url.URL, error) {
+	path, err := feed.path()
+	if err != nil {
+		return nil, err
+	}
+
+	u := s.client.feedsURL.ResolveReference(&url.URL{Path: format.String() + "/" + path})
+	if count > 0 {
+		u.RawQuery = url.Values{"count": {strconv.Itoa(count)}}.Encode()
+	}
+	return u, nil
+}
+
+// Fetch retrieves feed in the given format and returns its bookmarks. Posts
+// from feeds have no Hash, and Author is set to the user who saved them.
+func (s *FeedsService) Fetch(feed Feed, format FeedFormat, count int) ([]*Post,
+	*http.Response, error) {
+	u, err := s.URL(feed, format, count)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", s.client.userAgent)
+
+	if format == FeedRSS {
+		var result struct {
+			Items []*feedRSSItem `xml:"item"`
+		}
+		resp, err := s.client.do(req, &result, feedsEndpoint, xmlCodec)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		posts := make([]*Post, len(result.Items))
+		for i, v := range result.Items {
+			if posts[i], err = v.post(); err != nil {
+				return nil, resp, err
+			}
+		}
+		return posts, resp, nil
+	}
+
+	var result []*feedJSONItem
+	resp, err := s.client.do(req, &result, feedsEndpoint, jsonCodec)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	posts := make([]*Post, len(result))
+	for i, v := range result {
+		if posts[i], err = v.post(); err != nil {
+			return nil, resp, err
+		}
+	}
+	return posts, resp, nil
+}
+
+type feedJSONItem struct {
+	URL         string   `json:"u"`
+	Title       string   `json:"d"`
+	Description string   `json:"n"`
+	Time        string   `json:"dt"`
+	Author      string   `json:"a"`
+	Tags        []string `json:"t"`
+}
+
+func (item *feedJSONItem) post() (*Post, error) {
+	dt, err := time.Parse(time.RFC3339, item.Time)
+	if err != nil {
+		return nil, &FieldError{Field: "dt", Value: item.Time, Err: err}
+	}
+
+	// Untagged bookmarks come with a single empty tag.
+	var tags []string
+	for _, t := range item.Tags {
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return &Post{
+		Title:       item.Title,
+		Description: item.Description,
+		URL:         item.URL,
+		Tags:        tags,
+		Time:        &dt,
+		Author:      item.Author,
+	}, nil
+}
+
+// feedRSSItem is an item of the RSS 1.0 (RDF) feeds.
+type feedRSSItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"date"`
+	Creator     string `xml:"creator"`
+	Subject     string `xml:"subject"`
+}
+
+func (item *feedRSSItem) post() (*Post, error) {
+	dt, err := time.Parse(time.RFC3339, item.Date)
+	if err != nil {
+		return nil, &FieldError{Field: "dc:date", Value: item.Date, Err: err}
+	}
+
+	return &Post{
+		Title:       item.Title,
+		Description: item.Description,
+		URL:         item.Link,
+		Tags:        strings.Fields(item.Subject),
+		Time:        &dt,
+		Author:      item.Creator,
+	}, nil
+}
+
+var jsonCodec = codec{
+	accepts: isJSON,
+	decode: func(r io.Reader, v interface{}) error {
+		return json.NewDecoder(r).Decode(v)
+	},
+}
+
+// isJSON reports whether contentType names a JSON media type. A missing
+// content type is accepted, as is text/plain, which some servers send for
+// JSON.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mt {
+	case "application/json", "text/json", "text/javascript", "text/plain":
+		return true
+	}
+	return strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,113 @@
+package pin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var feedsURLTests = []struct {
+	feed   Feed
+	format FeedFormat
+	count  int
+	out    string
+}{
+	{UserFeed("user"), FeedJSON, 0, "https://feeds.pinboard.in/json/u:user/"},
+	{UserFeed("user", "go", "web"), FeedRSS, 0, "https://feeds.pinboard.in/rss/u:user/t:go/t:web/"},
+	{PrivateFeed("key", "user"), FeedJSON, 50, "https://feeds.pinboard.in/json/secret:key/u:user/?count=50"},
+	{NetworkFeed("key", "user"), FeedJSON, 0, "https://feeds.pinboard.in/json/secret:key/u:user/network/"},
+	{ToReadFeed("key", "user"), FeedRSS, 0, "https://feeds.pinboard.in/rss/secret:key/u:user/toread/"},
+	{PopularFeed(), FeedJSON, 0, "https://feeds.pinboard.in/json/popular/"},
+	{RecentFeed(), FeedJSON, 10, "https://feeds.pinboard.in/json/recent/?count=10"},
+}
+
+func TestFeedsURL(t *testing.T) {
+	for _, tt := range feedsURLTests {
+		u, err := client.Feeds.URL(tt.feed, tt.format, tt.count)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if u.String() != tt.out {
+			t.Errorf("Wrong feed URL expected %s got %s", tt.out, u)
+		}
+	}
+}
+
+func TestFeedsURLErrors(t *testing.T) {
+	feeds := []Feed{
+		UserFeed(""),
+		UserFeed("user", "a", "b", "c", "d"),
+		NetworkFeed("", "user"),
+		{Section: "bogus"},
+	}
+	for _, f := range feeds {
+		if _, err := client.Feeds.URL(f, FeedJSON, 0); err == nil {
+			t.Errorf("Expected error for feed %+v", f)
+		}
+	}
+}
+
+func newFeedsTestClient(t *testing.T) (*Client, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/u:user/":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(readTestdata("feeds_user.json")))
+		case "/rss/u:user/":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(readTestdata("feeds_user.xml")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	c, err := New(WithFeedsURL(srv.URL))
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return c, srv.Close
+}
+
+func TestFeedsFetch(t *testing.T) {
+	c, done := newFeedsTestClient(t)
+	defer done()
+
+	for _, format := range []FeedFormat{FeedJSON, FeedRSS} {
+		posts, _, err := c.Feeds.Fetch(UserFeed("user"), format, 0)
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if len(posts) != 2 {
+			t.Errorf("%s: Retrieved wrong amount - expected 2 got %d", format, len(posts))
+			continue
+		}
+
+		p := posts[0]
+		if p.URL != "https://golang.org/doc/effective_go.html" || p.Title != "Effective Go" ||
+			p.Description != "Tips for writing clear, idiomatic Go code." || p.Author != "user" {
+			t.Errorf("%s: Wrong post %+v", format, p)
+		}
+		if len(p.Tags) != 2 || p.Tags[0] != "golang" {
+			t.Errorf("%s: Wrong tags %v", format, p.Tags)
+		}
+		if p.Time.Unix() != 1402529143 {
+			t.Errorf("%s: Wrong time %s", format, p.Time)
+		}
+		if len(posts[1].Tags) != 0 {
+			t.Errorf("%s: Expected untagged post got %v", format, posts[1].Tags)
+		}
+	}
+}
+
+func TestFeedsFetchNotFound(t *testing.T) {
+	c, done := newFeedsTestClient(t)
+	defer done()
+
+	_, _, err := c.Feeds.Fetch(UserFeed("nobody"), FeedJSON, 0)
+	if serr, ok := err.(*StatusError); !ok || serr.Response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 *StatusError got %T (%v)", err, err)
+	}
+}
@@ -0,0 +1,51 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	for _, tt := range []struct {
+		data string
+		perm os.FileMode
+	}{
+		{"first", 0644},
+		{"second", 0600},
+	} {
+		if err := WriteFile(path, []byte(tt.data), tt.perm); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.data {
+			t.Errorf("Expected %q got %q", tt.data, data)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != tt.perm {
+			t.Errorf("Expected mode %v got %v", tt.perm, info.Mode().Perm())
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Temporary files left behind: %d files", len(files))
+	}
+}
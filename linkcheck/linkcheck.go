@@ -0,0 +1,335 @@
+// Package linkcheck finds bookmarks whose links have stopped working.
+package linkcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// Defaults used by a zero Checker.
+const (
+	DefaultConcurrency = 8
+	DefaultHostDelay   = time.Second
+	DefaultTimeout     = 15 * time.Second
+)
+
+// DefaultDeadTag is the tag conventionally given to dead bookmarks.
+const DefaultDeadTag = "dead-link"
+
+// Status classifies the outcome of checking a link.
+type Status int
+
+const (
+	OK Status = iota
+	Redirect
+	NotFound
+	Gone
+	ClientError // any other 4xx status
+	ServerError // any 5xx status
+	DNSFailure  // the host does not exist
+	Timeout
+	TLSError
+	ConnectionError // the connection failed for another reason
+	InvalidURL      // not an http or https URL, so it was not checked
+)
+
+var statusNames = map[Status]string{
+	OK:              "ok",
+	Redirect:        "redirect",
+	NotFound:        "not found",
+	Gone:            "gone",
+	ClientError:     "client error",
+	ServerError:     "server error",
+	DNSFailure:      "dns failure",
+	Timeout:         "timeout",
+	TLSError:        "tls error",
+	ConnectionError: "connection error",
+	InvalidURL:      "invalid url",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Dead reports whether the status means the link is gone for good, rather
+// than failing in a way that may be temporary. InvalidURL is not dead: such
+// links, like mailto: ones, are never checked.
+func (s Status) Dead() bool {
+	return s == NotFound || s == Gone || s == DNSFailure
+}
+
+// Result is the outcome of checking one post.
+type Result struct {
+	Post       *pin.Post
+	Status     Status
+	StatusCode int    // the HTTP status, if a response was received
+	Location   string // the redirect target, for Redirect
+	Err        error  // the request error, if no response was received
+}
+
+// Checker checks the links of posts concurrently while limiting how often
+// each host is contacted.
+type Checker struct {
+	// Client sends the requests. Redirects are never followed, so that
+	// they can be reported. If nil, a client with Timeout is used.
+	Client *http.Client
+
+	// Concurrency is the number of links checked at once. Zero means
+	// DefaultConcurrency.
+	Concurrency int
+
+	// HostDelay is the minimum time between two requests to the same
+	// host. Zero means DefaultHostDelay; a negative value disables it.
+	HostDelay time.Duration
+
+	// Timeout bounds each request when Client is nil. Zero means
+	// DefaultTimeout.
+	Timeout time.Duration
+
+	// UserAgent is sent with each request if set.
+	UserAgent string
+}
+
+// Check checks the link of every post and returns the results in the same
+// order as posts. It stops early, leaving later results as ConnectionError,
+// if ctx is done.
+func (c *Checker) Check(ctx context.Context, posts []*pin.Post) []Result {
+	client := c.client()
+	gate := &hostGate{delay: c.HostDelay, next: make(map[string]time.Time)}
+	if gate.delay == 0 {
+		gate.delay = DefaultHostDelay
+	}
+	workers := c.Concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+
+	results := make([]Result, len(posts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.check(ctx, client, gate, posts[i])
+			}
+		}()
+	}
+
+feed:
+	for i := range posts {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for ; i < len(posts); i++ {
+				results[i] = Result{Post: posts[i], Status: ConnectionError, Err: ctx.Err()}
+			}
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (c *Checker) client() *http.Client {
+	var client http.Client
+	if c.Client != nil {
+		client = *c.Client
+	} else {
+		client.Timeout = c.Timeout
+		if client.Timeout == 0 {
+			client.Timeout = DefaultTimeout
+		}
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+func (c *Checker) check(ctx context.Context, client *http.Client, gate *hostGate,
+	p *pin.Post) Result {
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		if err == nil {
+			err = errors.New("not an absolute http or https URL")
+		}
+		return Result{Post: p, Status: InvalidURL, Err: err}
+	}
+
+	resp, err := c.request(ctx, client, gate, "HEAD", u)
+	if err == nil && headUnsupported(resp.StatusCode) {
+		resp, err = c.request(ctx, client, gate, "GET", u)
+	}
+	if err != nil {
+		return Result{Post: p, Status: classifyError(err), Err: err}
+	}
+
+	r := Result{Post: p, Status: classifyStatus(resp.StatusCode), StatusCode: resp.StatusCode}
+	if r.Status == Redirect {
+		if loc, err := resp.Location(); err == nil {
+			r.Location = loc.String()
+		}
+	}
+	return r
+}
+
+// request sends a single request once the host gate allows it and closes the
+// response body.
+func (c *Checker) request(ctx context.Context, client *http.Client, gate *hostGate,
+	method string, u *url.URL) (*http.Response, error) {
+	if err := gate.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.CopyN(ioutil.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+	return resp, nil
+}
+
+// headUnsupported reports whether a HEAD response suggests the server does
+// not handle HEAD properly and a GET should be tried instead.
+func headUnsupported(code int) bool {
+	return code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented ||
+		code == http.StatusForbidden
+}
+
+func classifyStatus(code int) Status {
+	switch {
+	case code == http.StatusNotFound:
+		return NotFound
+	case code == http.StatusGone:
+		return Gone
+	case code >= 300 && code < 400:
+		return Redirect
+	case code >= 400 && code < 500:
+		return ClientError
+	case code >= 500:
+		return ServerError
+	}
+	return OK
+}
+
+func classifyError(err error) Status {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// Only a host that does not exist is dead; a resolver that
+		// timed out or failed says nothing about the link.
+		switch {
+		case dnsErr.IsNotFound:
+			return DNSFailure
+		case dnsErr.IsTimeout:
+			return Timeout
+		}
+		return ConnectionError
+	}
+
+	var (
+		unknownAuth  x509.UnknownAuthorityError
+		hostErr      x509.HostnameError
+		certErr      x509.CertificateInvalidError
+		recordErr    tls.RecordHeaderError
+		verification *tls.CertificateVerificationError
+	)
+	if errors.As(err, &unknownAuth) || errors.As(err, &hostErr) ||
+		errors.As(err, &certErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &verification) {
+		return TLSError
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout
+	}
+	return ConnectionError
+}
+
+// hostGate spaces out requests to the same host.
+type hostGate struct {
+	delay time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func (g *hostGate) wait(ctx context.Context, host string) error {
+	if g.delay < 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	now := time.Now()
+	at := g.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	g.next[host] = at.Add(g.delay)
+	g.mu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+// TagDead adds tag to every post in results whose link is dead and returns
+// the number of posts updated. Posts are saved with pin.SavePost, so nothing
+// else about them changes. Posts that already have the tag are skipped.
+func TagDead(posts pin.PostsAPI, results []Result, tag string) (int, error) {
+	n := 0
+	for _, r := range results {
+		if !r.Status.Dead() || hasTag(r.Post, tag) {
+			continue
+		}
+
+		p := *r.Post
+		p.Tags = append(append([]string(nil), r.Post.Tags...), tag)
+		if _, err := pin.SavePost(posts, &p, true); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func hasTag(p *pin.Post, tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
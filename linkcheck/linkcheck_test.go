@@ -0,0 +1,181 @@
+package linkcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func newServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheck(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	tests := []struct {
+		url    string
+		status Status
+		code   int
+	}{
+		{srv.URL + "/ok", OK, 200},
+		{srv.URL + "/missing", NotFound, 404},
+		{srv.URL + "/gone", Gone, 410},
+		{srv.URL + "/moved", Redirect, 301},
+		{srv.URL + "/nohead", OK, 200},
+		{srv.URL + "/broken", ServerError, 500},
+		{srv.URL + "/slow", Timeout, 0},
+		{"mailto:someone@example.org", InvalidURL, 0},
+	}
+
+	var posts []*pin.Post
+	for _, tt := range tests {
+		posts = append(posts, &pin.Post{URL: tt.url})
+	}
+
+	c := &Checker{HostDelay: -1, Timeout: 50 * time.Millisecond}
+	results := c.Check(context.Background(), posts)
+	for i, tt := range tests {
+		r := results[i]
+		if r.Post != posts[i] {
+			t.Errorf("%s: results out of order", tt.url)
+		}
+		if r.Status != tt.status || r.StatusCode != tt.code {
+			t.Errorf("%s: expected %s (%d) got %s (%d, %v)", tt.url, tt.status, tt.code,
+				r.Status, r.StatusCode, r.Err)
+		}
+	}
+	if loc := results[3].Location; loc != srv.URL+"/ok" {
+		t.Errorf("Wrong redirect location %s", loc)
+	}
+}
+
+func TestCheckTLSError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	// The test server's certificate is not trusted by the default client.
+	results := (&Checker{}).Check(context.Background(), []*pin.Post{{URL: srv.URL}})
+	if r := results[0]; r.Status != TLSError {
+		t.Errorf("Expected tls error got %s (%v)", r.Status, r.Err)
+	}
+}
+
+func TestClassifyDNSError(t *testing.T) {
+	tests := []struct {
+		err    *net.DNSError
+		status Status
+	}{
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, DNSFailure},
+		{&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, Timeout},
+		{&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}, ConnectionError},
+	}
+	for _, tt := range tests {
+		err := &net.OpError{Op: "dial", Err: tt.err}
+		if s := classifyError(err); s != tt.status {
+			t.Errorf("%s: expected %s got %s", tt.err.Err, tt.status, s)
+		}
+		if s := classifyError(err); s.Dead() != (tt.status == DNSFailure) {
+			t.Errorf("%s: wrong Dead for %s", tt.err.Err, s)
+		}
+	}
+}
+
+func TestCheckHostDelay(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	posts := []*pin.Post{{URL: srv.URL + "/a"}, {URL: srv.URL + "/b"}, {URL: srv.URL + "/c"}}
+	c := &Checker{Concurrency: 3, HostDelay: 30 * time.Millisecond}
+	for _, r := range c.Check(context.Background(), posts) {
+		if r.Status != OK {
+			t.Errorf("%s: %s (%v)", r.Post.URL, r.Status, r.Err)
+		}
+	}
+
+	if len(times) != 3 {
+		t.Fatalf("Expected 3 requests got %d", len(times))
+	}
+	if d := times[2].Sub(times[0]); d < 55*time.Millisecond {
+		t.Errorf("Requests to the same host not spaced out, took %s", d)
+	}
+}
+
+func TestTagDead(t *testing.T) {
+	posts := &pin.FakePostsService{}
+	results := []Result{
+		{Post: &pin.Post{URL: "http://a.example", Title: "A", Tags: []string{"one"}, Shared: true}, Status: NotFound},
+		{Post: &pin.Post{URL: "http://b.example"}, Status: OK},
+		{Post: &pin.Post{URL: "http://c.example", Tags: []string{DefaultDeadTag}}, Status: Gone},
+		{Post: &pin.Post{URL: "http://d.example"}, Status: Timeout},
+	}
+
+	n, err := TagDead(posts, results, DefaultDeadTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 post tagged got %d", n)
+	}
+
+	calls := posts.AddCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 call to Add got %d", len(calls))
+	}
+	c := calls[0]
+	if c.URL != "http://a.example" || c.Title != "A" || !c.Replace || !c.Shared ||
+		len(c.Tags) != 2 || c.Tags[1] != DefaultDeadTag {
+		t.Errorf("Wrong post saved %+v", c)
+	}
+	if len(results[0].Post.Tags) != 1 {
+		t.Error("TagDead modified the original post")
+	}
+}
+
+func TestTagDeadSkipsUnchecked(t *testing.T) {
+	results := (&Checker{}).Check(context.Background(), []*pin.Post{{URL: "mailto:someone@example.org"}})
+	if results[0].Status != InvalidURL {
+		t.Fatalf("Expected invalid url got %s", results[0].Status)
+	}
+
+	posts := &pin.FakePostsService{}
+	if n, err := TagDead(posts, results, DefaultDeadTag); err != nil || n != 0 {
+		t.Errorf("Expected nothing tagged got %d, %v", n, err)
+	}
+	if calls := posts.AddCalls(); len(calls) != 0 {
+		t.Errorf("Unexpected calls to Add %+v", calls)
+	}
+}
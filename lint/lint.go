@@ -0,0 +1,221 @@
+// Package lint checks bookmarks and tags against hygiene rules, such as
+// every bookmark having tags and a description, and fixes what it can.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zachlatta/pin"
+)
+
+// Severity ranks findings.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes s as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if strings.EqualFold(string(text), name) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("lint: unknown severity %q", text)
+}
+
+// Rule is a check run over each post, each tag, or both. A check returns an
+// empty message if its subject passes.
+type Rule struct {
+	Name     string
+	Severity Severity
+	Post     func(p *pin.Post) (message string, fix *Fix)
+	Tag      func(t *pin.Tag) (message string, fix *Fix)
+}
+
+// Fix is an automatic correction for a finding.
+type Fix struct {
+	Description string
+
+	// Edit changes a copy of the post, which is then saved in place of the
+	// original. It may do network I/O, such as fetching the page title.
+	Edit func(ctx context.Context, p *pin.Post) error
+
+	// RenameTag is the name the tag of a tag finding is renamed to.
+	RenameTag string
+}
+
+// Finding is a problem found by a rule. Exactly one of Post and Tag is set.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Post     *pin.Post
+	Tag      *pin.Tag
+	Message  string
+	Fix      *Fix // nil if the problem has to be fixed by hand
+}
+
+// Subject returns the URL of the post or the name of the tag the finding is
+// about.
+func (f *Finding) Subject() string {
+	if f.Post != nil {
+		return f.Post.URL
+	}
+	if f.Tag != nil {
+		return f.Tag.Name
+	}
+	return ""
+}
+
+// Lint runs rules over posts and tags and returns the findings, those for
+// posts first, in the order of posts, tags and rules.
+func Lint(rules []Rule, posts []*pin.Post, tags []*pin.Tag) []Finding {
+	var findings []Finding
+	for _, p := range posts {
+		for _, r := range rules {
+			if r.Post == nil {
+				continue
+			}
+			if msg, fix := r.Post(p); msg != "" {
+				findings = append(findings, Finding{Rule: r.Name, Severity: r.Severity,
+					Post: p, Message: msg, Fix: fix})
+			}
+		}
+	}
+	for _, t := range tags {
+		for _, r := range rules {
+			if r.Tag == nil {
+				continue
+			}
+			if msg, fix := r.Tag(t); msg != "" {
+				findings = append(findings, Finding{Rule: r.Name, Severity: r.Severity,
+					Tag: t, Message: msg, Fix: fix})
+			}
+		}
+	}
+	return findings
+}
+
+// Max returns the highest severity among findings, or -1 if there are none.
+// It is handy for choosing an exit status.
+func Max(findings []Finding) Severity {
+	max := Severity(-1)
+	for _, f := range findings {
+		if f.Severity > max {
+			max = f.Severity
+		}
+	}
+	return max
+}
+
+// ApplyFixes carries out the fixes of findings. All edits to one post are
+// made to a single copy, which is saved once, replacing the original. Tags
+// are renamed after the posts are saved. It stops at the first error and
+// returns the number of fixes applied.
+func ApplyFixes(ctx context.Context, posts pin.PostsAPI, tags pin.TagsAPI,
+	findings []Finding) (int, error) {
+	var order []*pin.Post
+	edits := make(map[*pin.Post][]*Fix)
+	var renames []Finding
+	for _, f := range findings {
+		switch {
+		case f.Fix == nil:
+		case f.Post != nil && f.Fix.Edit != nil:
+			if edits[f.Post] == nil {
+				order = append(order, f.Post)
+			}
+			edits[f.Post] = append(edits[f.Post], f.Fix)
+		case f.Tag != nil && f.Fix.RenameTag != "":
+			renames = append(renames, f)
+		}
+	}
+
+	n := 0
+	for _, orig := range order {
+		p := *orig
+		p.Tags = append([]string(nil), orig.Tags...)
+		for _, fix := range edits[orig] {
+			if err := fix.Edit(ctx, &p); err != nil {
+				return n, fmt.Errorf("lint: fixing %s: %v", orig.URL, err)
+			}
+		}
+		if _, err := pin.SavePost(posts, &p, true); err != nil {
+			return n, fmt.Errorf("lint: saving %s: %v", orig.URL, err)
+		}
+		n += len(edits[orig])
+	}
+
+	for _, f := range renames {
+		if _, err := tags.Rename(f.Fix.RenameTag, f.Tag.Name); err != nil {
+			return n, fmt.Errorf("lint: renaming tag %s: %v", f.Tag.Name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// WriteText writes findings to w as aligned columns of severity, rule,
+// subject and message. Fixable findings are marked with the fix.
+func WriteText(w io.Writer, findings []Finding) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, f := range findings {
+		msg := f.Message
+		if f.Fix != nil {
+			msg += " (fix: " + f.Fix.Description + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Rule, f.Subject(), msg)
+	}
+	return tw.Flush()
+}
+
+type jsonFinding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	URL      string   `json:"url,omitempty"`
+	Tag      string   `json:"tag,omitempty"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// WriteJSON writes findings to w as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	out := make([]jsonFinding, len(findings))
+	for i, f := range findings {
+		out[i] = jsonFinding{Rule: f.Rule, Severity: f.Severity, Message: f.Message}
+		if f.Post != nil {
+			out[i].URL = f.Post.URL
+		}
+		if f.Tag != nil {
+			out[i].Tag = f.Tag.Name
+		}
+		if f.Fix != nil {
+			out[i].Fix = f.Fix.Description
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
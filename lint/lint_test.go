@@ -0,0 +1,159 @@
+package lint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zachlatta/pin"
+)
+
+var posts = []*pin.Post{
+	{URL: "https://x.com/a", Title: "A", Description: "about a", Tags: []string{"go"}},
+	{URL: "https://x.com/b", Title: "x.com/b/", Tags: []string{"go"}},
+	{URL: "https://x.com/c", Title: "C", Description: "about c"},
+}
+
+var tags = []*pin.Tag{
+	{Name: "Go", Count: 2},
+	{Name: "golang", Count: 4},
+	{Name: "misc", Count: 1},
+}
+
+func TestLint(t *testing.T) {
+	rules := []Rule{Untagged(), EmptyDescription(), TitleIsURL(nil),
+		Taxonomy([]string{"go"}, map[string]string{"golang": "go"})}
+	findings := Lint(rules, posts, tags)
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, fmt.Sprintf("%s %s %s %v", f.Severity, f.Rule, f.Subject(), f.Fix != nil))
+	}
+	expected := []string{
+		"info empty-description https://x.com/b false",
+		"warning title-is-url https://x.com/b false",
+		"warning untagged https://x.com/c false",
+		"error taxonomy golang true",
+		"error taxonomy misc false",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong findings:\n%s", strings.Join(got, "\n"))
+	}
+	if Max(findings) != Error {
+		t.Errorf("Expected max severity error got %s", Max(findings))
+	}
+	if Max(nil) >= Info {
+		t.Error("Expected max severity of no findings to be below info")
+	}
+}
+
+func TestConfigRules(t *testing.T) {
+	var c Config
+	err := json.Unmarshal([]byte(`{
+		"disable": ["empty-description"],
+		"severity": {"untagged": "error"},
+		"taxonomy": ["go"]
+	}`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := c.Rules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name)
+		if r.Name == RuleUntagged && r.Severity != Error {
+			t.Errorf("Expected untagged to be an error got %s", r.Severity)
+		}
+	}
+	if strings.Join(names, " ") != "untagged title-is-url taxonomy" {
+		t.Errorf("Wrong rules %v", names)
+	}
+
+	c.Disable = []string{"no-such-rule"}
+	if _, err := c.Rules(nil); err == nil {
+		t.Error("Expected error for unknown rule")
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><TITLE>\n  Page &amp; title\n</TITLE></head></html>")
+	}))
+	defer srv.Close()
+
+	p := &pin.Post{URL: srv.URL + "/b", Title: srv.URL + "/b", Tags: []string{"go"}, Shared: true}
+	retag := Rule{
+		Name: "retag",
+		Post: func(p *pin.Post) (string, *Fix) {
+			return "retag", &Fix{Description: "add tag", Edit: func(ctx context.Context, p *pin.Post) error {
+				p.Tags = append(p.Tags, "web")
+				return nil
+			}}
+		},
+	}
+	rules := []Rule{TitleIsURL(&TitleFetcher{}), retag,
+		Taxonomy(nil, map[string]string{"golang": "go"})}
+	findings := Lint(rules, []*pin.Post{p}, []*pin.Tag{{Name: "golang"}})
+
+	posts := &pin.FakePostsService{}
+	tags := &pin.FakeTagsService{}
+	n, err := ApplyFixes(context.Background(), posts, tags, findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 fixes got %d", n)
+	}
+
+	adds := posts.AddCalls()
+	if len(adds) != 1 {
+		t.Fatalf("Expected post to be saved once got %d", len(adds))
+	}
+	a := adds[0]
+	if a.Title != "Page & title" || strings.Join(a.Tags, " ") != "go web" || !a.Replace || !a.Shared {
+		t.Errorf("Wrong save %+v", a)
+	}
+	if len(p.Tags) != 1 {
+		t.Error("Original post was modified")
+	}
+
+	renames := tags.RenameCalls()
+	if len(renames) != 1 || renames[0].NewTag != "go" || renames[0].OldTag != "golang" {
+		t.Errorf("Wrong renames %+v", renames)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	findings := Lint([]Rule{Untagged(), Taxonomy(nil, map[string]string{"misc": "other"})}, posts, tags)
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "warning  untagged  https://x.com/c  bookmark has no tags\n") ||
+		!strings.Contains(buf.String(), "(fix: rename to other)") {
+		t.Errorf("Wrong text output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	var out []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 4 || out[0]["severity"] != "warning" || out[0]["url"] != "https://x.com/c" ||
+		out[3]["tag"] != "misc" || out[3]["fix"] != "rename to other" {
+		t.Errorf("Wrong JSON output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,247 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// Names of the built-in rules.
+const (
+	RuleUntagged         = "untagged"
+	RuleEmptyDescription = "empty-description"
+	RuleTitleIsURL       = "title-is-url"
+	RuleTaxonomy         = "taxonomy"
+)
+
+// Untagged reports posts without tags.
+func Untagged() Rule {
+	return Rule{
+		Name:     RuleUntagged,
+		Severity: Warning,
+		Post: func(p *pin.Post) (string, *Fix) {
+			if len(p.Tags) == 0 {
+				return "bookmark has no tags", nil
+			}
+			return "", nil
+		},
+	}
+}
+
+// EmptyDescription reports posts without a description.
+func EmptyDescription() Rule {
+	return Rule{
+		Name:     RuleEmptyDescription,
+		Severity: Info,
+		Post: func(p *pin.Post) (string, *Fix) {
+			if strings.TrimSpace(p.Description) == "" {
+				return "bookmark has no description", nil
+			}
+			return "", nil
+		},
+	}
+}
+
+// TitleIsURL reports posts whose title is empty or just the URL. If titles
+// is not nil, the findings can be fixed by fetching the title of the page.
+func TitleIsURL(titles *TitleFetcher) Rule {
+	return Rule{
+		Name:     RuleTitleIsURL,
+		Severity: Warning,
+		Post: func(p *pin.Post) (string, *Fix) {
+			title := strings.TrimSpace(p.Title)
+			var msg string
+			switch {
+			case title == "":
+				msg = "bookmark has no title"
+			case sameURL(title, p.URL):
+				msg = "title is the URL"
+			default:
+				return "", nil
+			}
+			if titles == nil {
+				return msg, nil
+			}
+			return msg, &Fix{
+				Description: "fetch page title",
+				Edit: func(ctx context.Context, p *pin.Post) error {
+					t, err := titles.Fetch(ctx, p.URL)
+					if err != nil {
+						return err
+					}
+					p.Title = t
+					return nil
+				},
+			}
+		},
+	}
+}
+
+func sameURL(a, b string) bool {
+	trim := func(s string) string {
+		s = strings.TrimPrefix(s, "https://")
+		s = strings.TrimPrefix(s, "http://")
+		return strings.TrimSuffix(s, "/")
+	}
+	return strings.EqualFold(trim(a), trim(b))
+}
+
+// Taxonomy reports tags outside the allowed set, compared case-insensitively.
+// Tags that mapping renames to another tag can be fixed automatically.
+func Taxonomy(allowed []string, mapping map[string]string) Rule {
+	set := make(map[string]bool, len(allowed))
+	for _, t := range allowed {
+		set[strings.ToLower(t)] = true
+	}
+	return Rule{
+		Name:     RuleTaxonomy,
+		Severity: Error,
+		Tag: func(t *pin.Tag) (string, *Fix) {
+			if set[strings.ToLower(t.Name)] {
+				return "", nil
+			}
+			msg := fmt.Sprintf("tag is not in the taxonomy (used %d times)", t.Count)
+			to, ok := mapping[t.Name]
+			if !ok {
+				to, ok = mapping[strings.ToLower(t.Name)]
+			}
+			if !ok || to == "" {
+				return msg, nil
+			}
+			return msg, &Fix{Description: "rename to " + to, RenameTag: to}
+		},
+	}
+}
+
+// Config selects and tunes the built-in rules. It is usually loaded from a
+// JSON file shared by a team:
+//
+//	{
+//	  "disable": ["empty-description"],
+//	  "severity": {"untagged": "error"},
+//	  "taxonomy": ["go", "databases", "security"],
+//	  "tag_map": {"golang": "go", "db": "databases"}
+//	}
+type Config struct {
+	Disable  []string            `json:"disable"`
+	Severity map[string]Severity `json:"severity"`
+	Taxonomy []string            `json:"taxonomy"` // the taxonomy rule runs only if set
+	TagMap   map[string]string   `json:"tag_map"`
+}
+
+// LoadConfig reads a Config from the JSON file at path.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c Config
+	if err := json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, fmt.Errorf("lint: %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Rules returns the built-in rules as configured by c. Titles is used to fix
+// findings of the title-is-url rule and may be nil.
+func (c *Config) Rules(titles *TitleFetcher) ([]Rule, error) {
+	rules := []Rule{Untagged(), EmptyDescription(), TitleIsURL(titles)}
+	if len(c.Taxonomy) > 0 {
+		rules = append(rules, Taxonomy(c.Taxonomy, c.TagMap))
+	}
+
+	known := map[string]bool{RuleUntagged: true, RuleEmptyDescription: true,
+		RuleTitleIsURL: true, RuleTaxonomy: true}
+	disabled := make(map[string]bool)
+	for _, name := range c.Disable {
+		if !known[name] {
+			return nil, fmt.Errorf("lint: unknown rule %q", name)
+		}
+		disabled[name] = true
+	}
+	for name := range c.Severity {
+		if !known[name] {
+			return nil, fmt.Errorf("lint: unknown rule %q", name)
+		}
+	}
+
+	var out []Rule
+	for _, r := range rules {
+		if disabled[r.Name] {
+			continue
+		}
+		if s, ok := c.Severity[r.Name]; ok {
+			r.Severity = s
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// maxPage bounds how much of a page is read looking for its title.
+const maxPage = 512 << 10
+
+var titleRE = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// TitleFetcher looks up the titles of web pages.
+type TitleFetcher struct {
+	// Client sends the requests. If nil, a client with a 15 second timeout is
+	// used.
+	Client *http.Client
+
+	// UserAgent is sent with each request if set.
+	UserAgent string
+}
+
+// Fetch returns the contents of the <title> element of the page at urlStr.
+func (f *TitleFetcher) Fetch(ctx context.Context, urlStr string) (string, error) {
+	req, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("lint: %s: %s", urlStr, resp.Status)
+	}
+
+	page := make([]byte, maxPage)
+	n, err := io.ReadFull(resp.Body, page)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	m := titleRE.FindSubmatch(page[:n])
+	if m == nil {
+		return "", errors.New("lint: page has no title")
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	if title == "" {
+		return "", errors.New("lint: page has an empty title")
+	}
+	return title, nil
+}
@@ -0,0 +1,239 @@
+// Package mirror keeps a local copy of an account's bookmarks up to date
+// without calling posts/all, which Pinboard limits to once every five
+// minutes. It compares the per-day counts of posts/dates with those of the
+// local copy and fetches only the days that differ with posts/get.
+//
+// Days are compared in UTC. Edits that leave a day's count unchanged, such
+// as retagging a bookmark, are only picked up by a full refresh.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/internal/atomicfile"
+)
+
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DayChange is a day whose local and remote post counts differ.
+type DayChange struct {
+	Day    time.Time
+	Local  int
+	Remote int
+}
+
+// Plan lists the days to fetch again, oldest first.
+type Plan struct {
+	Days []DayChange
+}
+
+// Counts returns the number of posts saved each day.
+func Counts(posts []*pin.Post) map[time.Time]int {
+	counts := make(map[time.Time]int)
+	for _, p := range posts {
+		if p.Time != nil {
+			counts[day(*p.Time)]++
+		}
+	}
+	return counts
+}
+
+// PlanFetch compares the per-day counts of local with remote, as returned by
+// PostsService.Dates, and returns the days that differ.
+func PlanFetch(local []*pin.Post, remote []*pin.Date) *Plan {
+	localCounts := Counts(local)
+	remoteCounts := make(map[time.Time]int)
+	for _, d := range remote {
+		if d.Date != nil {
+			remoteCounts[day(*d.Date)] += d.Count
+		}
+	}
+
+	plan := &Plan{}
+	for d, n := range remoteCounts {
+		if localCounts[d] != n {
+			plan.Days = append(plan.Days, DayChange{d, localCounts[d], n})
+		}
+	}
+	for d, n := range localCounts {
+		if _, ok := remoteCounts[d]; !ok {
+			plan.Days = append(plan.Days, DayChange{d, n, 0})
+		}
+	}
+	sort.Slice(plan.Days, func(i, j int) bool { return plan.Days[i].Day.Before(plan.Days[j].Day) })
+	return plan
+}
+
+// Mirror is a local copy of the bookmarks of an account. A Mirror with only
+// Posts set is empty and ready to sync. It is not safe for concurrent use.
+type Mirror struct {
+	// Posts is used to fetch changes.
+	Posts pin.PostsAPI
+
+	// Delay is the pause between fetching days, to stay within the API rate
+	// limit.
+	Delay time.Duration
+
+	updated time.Time
+	byURL   map[string]*pin.Post
+}
+
+// New returns an empty mirror fetching through posts.
+func New(posts pin.PostsAPI) *Mirror {
+	return &Mirror{Posts: posts, byURL: make(map[string]*pin.Post)}
+}
+
+// Updated returns the last update time of the account when the mirror was
+// last synced.
+func (m *Mirror) Updated() time.Time {
+	return m.updated
+}
+
+// All returns the mirrored posts, newest first.
+func (m *Mirror) All() []*pin.Post {
+	posts := make([]*pin.Post, 0, len(m.byURL))
+	for _, p := range m.byURL {
+		posts = append(posts, p)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		a, b := posts[i], posts[j]
+		if (a.Time == nil) != (b.Time == nil) {
+			return b.Time == nil
+		}
+		if a.Time != nil && !a.Time.Equal(*b.Time) {
+			return a.Time.After(*b.Time)
+		}
+		return a.URL < b.URL
+	})
+	return posts
+}
+
+// Replace sets the mirrored posts, for example to the result of a full
+// refresh with PostsService.All, and records updated as the time of the
+// sync.
+func (m *Mirror) Replace(posts []*pin.Post, updated time.Time) {
+	m.byURL = make(map[string]*pin.Post, len(posts))
+	for _, p := range posts {
+		m.byURL[p.URL] = p
+	}
+	m.updated = updated
+}
+
+// Sync brings the mirror up to date. It does nothing if the account has not
+// changed since the last sync. Otherwise it fetches the days of the plan it
+// returns, replacing the local posts of each. It stops at the first error or
+// when ctx is done; the days fetched until then are kept, but the mirror is
+// not marked as synced, so the next Sync plans again.
+func (m *Mirror) Sync(ctx context.Context) (*Plan, error) {
+	updated, _, err := m.Posts.LastTimeUpdated()
+	if err != nil {
+		return nil, err
+	}
+	if updated != nil && updated.Equal(m.updated) {
+		return &Plan{}, nil
+	}
+
+	dates, _, err := m.Posts.Dates(nil)
+	if err != nil {
+		return nil, err
+	}
+	plan := PlanFetch(m.All(), dates)
+
+	fetched := 0
+	for _, c := range plan.Days {
+		var posts []*pin.Post
+		if c.Remote > 0 {
+			if fetched > 0 && m.Delay > 0 {
+				select {
+				case <-ctx.Done():
+					return plan, ctx.Err()
+				case <-time.After(m.Delay):
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return plan, err
+			}
+
+			dt := c.Day
+			if posts, _, err = m.Posts.Get(nil, &dt, ""); err != nil {
+				return plan, fmt.Errorf("mirror: fetching %s: %v", c.Day.Format("2006-01-02"), err)
+			}
+			fetched++
+		}
+		m.replaceDay(c.Day, posts)
+	}
+
+	if updated != nil {
+		m.updated = *updated
+	}
+	return plan, nil
+}
+
+func (m *Mirror) replaceDay(d time.Time, posts []*pin.Post) {
+	if m.byURL == nil {
+		m.byURL = make(map[string]*pin.Post, len(posts))
+	}
+	for u, p := range m.byURL {
+		if p.Time != nil && day(*p.Time).Equal(d) {
+			delete(m.byURL, u)
+		}
+	}
+	for _, p := range posts {
+		m.byURL[p.URL] = p
+	}
+}
+
+type state struct {
+	Updated time.Time   `json:"updated"`
+	Posts   []*pin.Post `json:"posts"`
+}
+
+// Save writes the mirror to w as JSON.
+func (m *Mirror) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(state{m.updated, m.All()})
+}
+
+// Load replaces the contents of the mirror with those written by Save.
+func (m *Mirror) Load(r io.Reader) error {
+	var s state
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+	m.Replace(s.Posts, s.Updated)
+	return nil
+}
+
+// SaveFile saves the mirror to the file at path. The file is replaced
+// atomically, so a failed save leaves the previous copy intact.
+func (m *Mirror) SaveFile(path string) error {
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// LoadFile loads a mirror saved with SaveFile. A missing file leaves the
+// mirror empty.
+func (m *Mirror) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Load(f)
+}
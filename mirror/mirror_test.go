@@ -0,0 +1,194 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func at(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func date(s string, count int) *pin.Date {
+	return &pin.Date{Date: at(s + "T00:00:00Z"), Count: count}
+}
+
+func TestPlanFetch(t *testing.T) {
+	local := []*pin.Post{
+		{URL: "a", Time: at("2020-01-01T10:00:00Z")},
+		{URL: "b", Time: at("2020-01-02T10:00:00Z")},
+		{URL: "c", Time: at("2020-01-02T11:00:00Z")},
+		{URL: "d", Time: at("2020-01-04T11:00:00Z")},
+	}
+	remote := []*pin.Date{
+		date("2020-01-01", 1),
+		date("2020-01-02", 3),
+		date("2020-01-03", 1),
+	}
+
+	plan := PlanFetch(local, remote)
+	expected := []DayChange{
+		{*at("2020-01-02T00:00:00Z"), 2, 3},
+		{*at("2020-01-03T00:00:00Z"), 0, 1},
+		{*at("2020-01-04T00:00:00Z"), 1, 0},
+	}
+	if !reflect.DeepEqual(plan.Days, expected) {
+		t.Errorf("Wrong plan %+v", plan.Days)
+	}
+}
+
+func TestSyncZeroMirror(t *testing.T) {
+	fake := &pin.FakePostsService{
+		LastTimeUpdatedStub: func() (*time.Time, *http.Response, error) {
+			return at("2020-01-05T00:00:00Z"), nil, nil
+		},
+		DatesStub: func([]string) ([]*pin.Date, *http.Response, error) {
+			return []*pin.Date{date("2020-01-02", 1)}, nil, nil
+		},
+		GetStub: func([]string, *time.Time, string) ([]*pin.Post, *http.Response, error) {
+			return []*pin.Post{{URL: "a", Time: at("2020-01-02T10:00:00Z")}}, nil, nil
+		},
+	}
+
+	m := &Mirror{Posts: fake}
+	if _, err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if all := m.All(); len(all) != 1 || all[0].URL != "a" {
+		t.Errorf("Wrong posts after sync %+v", all)
+	}
+}
+
+func TestSync(t *testing.T) {
+	updated := at("2020-01-05T00:00:00Z")
+	remote := map[string][]*pin.Post{
+		"2020-01-02": {
+			{URL: "b", Title: "B2", Time: at("2020-01-02T10:00:00Z")},
+			{URL: "e", Time: at("2020-01-02T12:00:00Z")},
+		},
+	}
+	fake := &pin.FakePostsService{
+		LastTimeUpdatedStub: func() (*time.Time, *http.Response, error) {
+			return updated, nil, nil
+		},
+		DatesStub: func([]string) ([]*pin.Date, *http.Response, error) {
+			return []*pin.Date{date("2020-01-01", 1), date("2020-01-02", 2)}, nil, nil
+		},
+		GetStub: func(tags []string, dt *time.Time, urlStr string) ([]*pin.Post, *http.Response, error) {
+			return remote[dt.Format("2006-01-02")], nil, nil
+		},
+	}
+
+	m := New(fake)
+	m.Replace([]*pin.Post{
+		{URL: "a", Time: at("2020-01-01T10:00:00Z")},
+		{URL: "b", Title: "B", Time: at("2020-01-02T10:00:00Z")},
+		{URL: "d", Time: at("2020-01-04T11:00:00Z")},
+	}, *at("2020-01-04T00:00:00Z"))
+
+	plan, err := m.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Days) != 2 {
+		t.Errorf("Expected 2 days in plan got %+v", plan.Days)
+	}
+	if calls := fake.GetCalls(); len(calls) != 1 || !calls[0].CreationTime.Equal(*at("2020-01-02T00:00:00Z")) {
+		t.Errorf("Expected only the changed day to be fetched got %+v", calls)
+	}
+
+	var urls []string
+	for _, p := range m.All() {
+		urls = append(urls, p.URL)
+	}
+	if !reflect.DeepEqual(urls, []string{"e", "b", "a"}) {
+		t.Errorf("Wrong posts after sync %v", urls)
+	}
+	if !m.Updated().Equal(*updated) {
+		t.Errorf("Wrong update time %s", m.Updated())
+	}
+
+	// Nothing changed since, so nothing is fetched.
+	if plan, err := m.Sync(context.Background()); err != nil || len(plan.Days) != 0 {
+		t.Errorf("Expected empty plan got %+v, %v", plan, err)
+	}
+	if n := len(fake.DatesCalls()); n != 1 {
+		t.Errorf("Expected Dates to be called once got %d", n)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(fake)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.All(), m.All()) || !loaded.Updated().Equal(m.Updated()) {
+		t.Error("Loaded mirror differs from saved one")
+	}
+}
+
+func TestSyncError(t *testing.T) {
+	fake := &pin.FakePostsService{
+		LastTimeUpdatedStub: func() (*time.Time, *http.Response, error) {
+			return at("2020-01-05T00:00:00Z"), nil, nil
+		},
+		DatesStub: func([]string) ([]*pin.Date, *http.Response, error) {
+			return []*pin.Date{date("2020-01-01", 1)}, nil, nil
+		},
+		GetStub: func([]string, *time.Time, string) ([]*pin.Post, *http.Response, error) {
+			return nil, nil, errors.New("boom")
+		},
+	}
+	m := New(fake)
+	if _, err := m.Sync(context.Background()); err == nil {
+		t.Fatal("Expected error from Get")
+	}
+	if !m.Updated().IsZero() {
+		t.Error("Mirror marked as synced after a failure")
+	}
+}
+
+func TestSaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mirror.json")
+
+	m := New(nil)
+	m.Replace([]*pin.Post{{URL: "a", Time: at("2020-01-01T10:00:00Z")}}, *at("2020-01-04T00:00:00Z"))
+	if err := m.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New(nil)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.All(), m.All()) || !loaded.Updated().Equal(m.Updated()) {
+		t.Error("Loaded mirror differs from saved one")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected only the mirror file got %d files", len(files))
+	}
+}
@@ -1,19 +1,115 @@
 package pin
 
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// timeLayoutNotes is the layout of the times of notes, which are in UTC.
+const timeLayoutNotes = "2006-01-02 15:04:05"
+
 // NotesService is the service for accessing Note-related calls from the
 // Pinboard API.
 type NotesService struct {
 	client *Client
 }
 
-// TODO
+// Note is a note from the user's notes. Text is only set by Get.
+type Note struct {
+	ID        string
+	Hash      string
+	Title     string
+	Text      string
+	Length    int
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+type noteResp struct {
+	ID        string `xml:"id,attr"`
+	Hash      string `xml:"hash"`
+	Title     string `xml:"title"`
+	Text      string `xml:"text"`
+	Length    string `xml:"length"`
+	CreatedAt string `xml:"created_at"`
+	UpdatedAt string `xml:"updated_at"`
+}
+
+func newNoteFromNoteResp(nresp *noteResp) (*Note, error) {
+	created, err := time.Parse(timeLayoutNotes, nresp.CreatedAt)
+	if err != nil {
+		return nil, &FieldError{Field: "created_at", Value: nresp.CreatedAt, Err: err}
+	}
+	updated, err := time.Parse(timeLayoutNotes, nresp.UpdatedAt)
+	if err != nil {
+		return nil, &FieldError{Field: "updated_at", Value: nresp.UpdatedAt, Err: err}
+	}
+	length, err := strconv.Atoi(nresp.Length)
+	if err != nil {
+		return nil, &FieldError{Field: "length", Value: nresp.Length, Err: err}
+	}
+
+	return &Note{
+		ID:        nresp.ID,
+		Hash:      nresp.Hash,
+		Title:     nresp.Title,
+		Text:      nresp.Text,
+		Length:    length,
+		CreatedAt: &created,
+		UpdatedAt: &updated,
+	}, nil
+}
+
+// List returns a list of the user's notes, without their text.
 //
 // https://pinboard.in/api#notes_list
-func (s *NotesService) List() {
+func (s *NotesService) List() ([]*Note, *http.Response, error) {
+	req, err := s.client.NewRequest("notes/list", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result struct {
+		Notes []*noteResp `xml:"note"`
+	}
+
+	resp, err := s.client.Do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	notes := make([]*Note, len(result.Notes))
+	for i, v := range result.Notes {
+		n, err := newNoteFromNoteResp(v)
+		if err != nil {
+			return nil, resp, err
+		}
+		notes[i] = n
+	}
+
+	return notes, resp, nil
 }
 
-// TODO
+// Get returns an individual user note, including its text.
 //
-//https://pinboard.in/api#notes_get
-func (s *NotesService) Get() {
+// https://pinboard.in/api#notes_get
+func (s *NotesService) Get(id string) (*Note, *http.Response, error) {
+	req, err := s.client.NewRequest("notes/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result noteResp
+	resp, err := s.client.Do(req, &result)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	n, err := newNoteFromNoteResp(&result)
+	if err != nil {
+		return nil, resp, err
+	}
+	return n, resp, nil
 }
@@ -0,0 +1,54 @@
+package pin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestNotesList(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/notes/list?auth_token=user%3Atoken",
+		httpmock.NewStringResponder(200, readFixture("notes_list")))
+
+	notes, _, err := client.Notes.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(notes) != 2 {
+		t.Fatalf("Wrong notes amount expected 2 got %d", len(notes))
+	}
+	n := notes[1]
+	if n.ID != "8e5d6964bb810e0050b0" || n.Title != "Shopping list" || n.Length != 23 || n.Text != "" {
+		t.Errorf("Wrong note %+v", n)
+	}
+	updated := time.Date(2011, time.November, 4, 19, 30, 51, 0, time.UTC)
+	if !n.UpdatedAt.Equal(updated) {
+		t.Errorf("Wrong update time expected %s got %s", updated, n.UpdatedAt)
+	}
+}
+
+func TestNotesGet(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/notes/8e5d6964bb810e0050b0?auth_token=user%3Atoken",
+		httpmock.NewStringResponder(200, readFixture("notes_get")))
+
+	n, _, err := client.Notes.Get("8e5d6964bb810e0050b0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Text != "eggs, milk, bread, tea" || n.Hash != "73b9c12b4e0adb6a1a86" {
+		t.Errorf("Wrong note %+v", n)
+	}
+	created := time.Date(2011, time.November, 2, 8, 12, 9, 0, time.UTC)
+	if !n.CreatedAt.Equal(created) {
+		t.Errorf("Wrong creation time expected %s got %s", created, n.CreatedAt)
+	}
+}
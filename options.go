@@ -0,0 +1,208 @@
+package pin
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logger is the interface used to report retries and other diagnostics. It is
+// satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option configures a Client. Options are only applied by New, which keeps a
+// Client's configuration immutable once it is in use.
+type Option func(*Client) error
+
+// WithHTTPClient sets the HTTP client used to send requests. A nil httpClient
+// selects http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) error {
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		c.client = httpClient
+		return nil
+	}
+}
+
+// WithAuthToken sets the token appended to every request. The token is
+// copied, so later changes to authToken do not affect the Client.
+func WithAuthToken(authToken *AuthToken) Option {
+	return func(c *Client) error {
+		if authToken == nil {
+			c.authToken = nil
+			return nil
+		}
+		tok := *authToken
+		c.authToken = &tok
+		return nil
+	}
+}
+
+// WithBaseURL sets the URL that relative API paths are resolved against. A
+// trailing slash is added if missing.
+func WithBaseURL(urlStr string) Option {
+	return func(c *Client) error {
+		u, err := parseRootURL(urlStr)
+		if err != nil {
+			return err
+		}
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithFeedsURL sets the URL that feed paths are resolved against. A trailing
+// slash is added if missing.
+func WithFeedsURL(urlStr string) Option {
+	return func(c *Client) error {
+		u, err := parseRootURL(urlStr)
+		if err != nil {
+			return err
+		}
+		c.feedsURL = u
+		return nil
+	}
+}
+
+func parseRootURL(urlStr string) (*url.URL, error) {
+	if !strings.HasSuffix(urlStr, "/") {
+		urlStr += "/"
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() {
+		return nil, errors.New("base URL must be absolute")
+	}
+	return u, nil
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		if ua == "" {
+			return errors.New("user agent must not be empty")
+		}
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithTimeout sets a time limit for each HTTP request, including reading the
+// response body. A zero timeout means no limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("timeout must not be negative")
+		}
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetry retries requests that fail with a transport error, a 429 or a 5xx
+// status up to maxRetries times. The delay starts at wait and doubles after
+// each attempt, but is never shorter than a Retry-After header asks for.
+func WithRetry(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) error {
+		if maxRetries < 0 {
+			return errors.New("max retries must not be negative")
+		}
+		if wait < 0 {
+			return errors.New("retry wait must not be negative")
+		}
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+		return nil
+	}
+}
+
+// WithLogger sets the logger used for diagnostics. Nothing is logged by
+// default.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithRateLimit spaces requests at least interval apart, across all
+// goroutines using the Client. Pinboard asks for one call every three seconds.
+func WithRateLimit(interval time.Duration) Option {
+	return func(c *Client) error {
+		if interval < 0 {
+			return errors.New("rate limit interval must not be negative")
+		}
+		c.rateInterval = interval
+		return nil
+	}
+}
+
+// WithMaxResponseSize limits the size of response bodies from endpoints
+// without a limit of their own. Larger responses fail with a
+// ResponseTooLargeError. Zero disables the limit.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("max response size must not be negative")
+		}
+		c.maxResponseSize = n
+		return nil
+	}
+}
+
+// WithEndpointMaxResponseSize limits the size of response bodies from a
+// single endpoint, given relative to the base URL, such as "posts/all". It
+// takes precedence over WithMaxResponseSize. Zero disables the limit.
+func WithEndpointMaxResponseSize(endpoint string, n int64) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("max response size must not be negative")
+		}
+		c.endpointLimits[strings.TrimPrefix(endpoint, "/")] = n
+		return nil
+	}
+}
+
+// WithTagPolicy makes PostsService.Add check the tags of new posts against
+// policy before sending them. What happens to posts that break the policy
+// depends on its Mode; unless they are rejected, violations are reported
+// through the logger. The policy is copied.
+func WithTagPolicy(policy *TagPolicy) Option {
+	return func(c *Client) error {
+		if policy == nil {
+			c.tagPolicy = nil
+			return nil
+		}
+		tp, err := compileTagPolicy(policy)
+		if err != nil {
+			return err
+		}
+		c.tagPolicy = tp
+		return nil
+	}
+}
+
+// PreAddFunc is a stage run by PostsService.Add on the post about to be
+// added. It may change the post, for example to add tags; an error aborts
+// the request.
+type PreAddFunc func(p *Post) error
+
+// WithPreAdd adds f to the stages run by PostsService.Add. Stages run in the
+// order they were added, before the tag policy is checked.
+func WithPreAdd(f PreAddFunc) Option {
+	return func(c *Client) error {
+		if f == nil {
+			return errors.New("pre-add func must not be nil")
+		}
+		c.preAdd = append(c.preAdd, f)
+		return nil
+	}
+}
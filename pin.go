@@ -1,20 +1,44 @@
+// Package pin is a client for the Pinboard API.
+//
+// A Client is configured with the options passed to New. There is no option
+// for the response format: the API is always asked for, and answers in, its
+// default XML format, which every service decodes. The JSON format is out of
+// scope.
 package pin
 
 import (
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	libraryVersion = "0.1"
 	defaultBaseURL = "https://api.pinboard.in/v1/"
 	userAgent      = "pin/" + libraryVersion
+
+	// defaultMaxResponseSize bounds responses from every endpoint without a
+	// limit of its own.
+	defaultMaxResponseSize = 8 << 20
+
+	// maxDrain is how much of an unread body is discarded before closing it,
+	// so that the connection can be reused.
+	maxDrain = 64 << 10
 )
 
+// defaultEndpointLimits holds the response size limits for endpoints that
+// legitimately return more than defaultMaxResponseSize.
+var defaultEndpointLimits = map[string]int64{
+	"posts/all": 256 << 20,
+}
+
 type AuthToken struct {
 	Username string
 	Token    string
@@ -24,16 +48,37 @@ func (t *AuthToken) String() string {
 	return fmt.Sprintf("%s:%s", t.Username, t.Token)
 }
 
+// Client manages communication with the Pinboard API. Its configuration is
+// fixed when it is constructed, so a Client is safe for concurrent use by
+// multiple goroutines.
 type Client struct {
 	client    *http.Client
 	authToken *AuthToken
-	BaseURL   *url.URL
-	UserAgent string
+	baseURL   *url.URL
+	feedsURL  *url.URL
+	userAgent string
+	timeout   time.Duration
+	logger    Logger
+	tagPolicy *tagPolicy
+	preAdd    []PreAddFunc
+
+	maxResponseSize int64
+	endpointLimits  map[string]int64
 
-	Posts *PostsService
-	Tags  *TagsService
-	User  *UserService
-	Notes *NotesService
+	maxRetries int
+	retryWait  time.Duration
+
+	// rateInterval is the minimum spacing between requests. nextRequest is
+	// the earliest time the next request may be sent and is guarded by rateMu.
+	rateInterval time.Duration
+	rateMu       sync.Mutex
+	nextRequest  time.Time
+
+	Posts PostsAPI
+	Tags  TagsAPI
+	User  UserAPI
+	Notes NotesAPI
+	Feeds FeedsAPI
 }
 
 // NewClient returns a new Pinboard API client. If a nil httpClient client is
@@ -41,29 +86,66 @@ type Client struct {
 // requests using HTTP Auth, you need to pass in an authenticated client - this
 // library does not handle authentication.
 func NewClient(httpClient *http.Client, authToken *AuthToken) *Client {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
+	// Neither option can fail, so there is no error to report.
+	c, _ := New(WithHTTPClient(httpClient), WithAuthToken(authToken))
+	return c
+}
 
-	}
+// New returns a new Pinboard API client configured by opts. Options are
+// applied in order and the first one to fail aborts construction.
+func New(opts ...Option) (*Client, error) {
 	baseURL, _ := url.Parse(defaultBaseURL)
+	feedsURL, _ := url.Parse(defaultFeedsURL)
 
 	c := &Client{
-		client:    httpClient,
-		authToken: authToken,
-		BaseURL:   baseURL,
-		UserAgent: userAgent,
+		client:          http.DefaultClient,
+		baseURL:         baseURL,
+		feedsURL:        feedsURL,
+		userAgent:       userAgent,
+		maxResponseSize: defaultMaxResponseSize,
+		endpointLimits:  make(map[string]int64),
+	}
+	for endpoint, n := range defaultEndpointLimits {
+		c.endpointLimits[endpoint] = n
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
 	}
+	if c.timeout > 0 {
+		// Copy the client so a shared one, such as http.DefaultClient, is
+		// left untouched.
+		hc := *c.client
+		hc.Timeout = c.timeout
+		c.client = &hc
+	}
+
 	c.Posts = &PostsService{c}
 	c.Tags = &TagsService{c}
 	c.User = &UserService{c}
 	c.Notes = &NotesService{c}
-	return c
+	c.Feeds = &FeedsService{c}
+	return c, nil
+}
+
+// BaseURL returns a copy of the URL that relative API paths are resolved
+// against.
+func (c *Client) BaseURL() *url.URL {
+	u := *c.baseURL
+	return &u
+}
+
+// UserAgent returns the User-Agent header sent with every request.
+func (c *Client) UserAgent() string {
+	return c.userAgent
 }
 
 // NewRequest constructs a new request to the Pinboard API. A relative URL can
 // be provided in urlStr, in which case it's resolved to the Client's BaseURL.
 // Relative URLs should always be specified without a preceding slash. If the
-// Client has an AuthToken set, then it is added to the urlParams.
+// Client has an AuthToken set, then it is added to the query string. urlParams
+// is copied and never modified, so it may be reused across requests.
 func (c *Client) NewRequest(urlStr string,
 	urlParams *url.Values) (*http.Request, error) {
 	rel, err := url.Parse(urlStr)
@@ -71,22 +153,24 @@ func (c *Client) NewRequest(urlStr string,
 		return nil, err
 	}
 
-	if c.authToken != nil {
-		tok := c.authToken.String()
-		if urlParams != nil {
-			urlParams.Add("auth_token", tok)
-		} else {
-			urlParams = &url.Values{"auth_token": {tok}}
+	params := url.Values{}
+	if urlParams != nil {
+		for k, v := range *urlParams {
+			params[k] = append([]string(nil), v...)
 		}
 	}
+	if c.authToken != nil {
+		params.Set("auth_token", c.authToken.String())
+	}
 
-	u := c.BaseURL.ResolveReference(rel)
-	u.RawQuery = urlParams.Encode()
+	u := c.baseURL.ResolveReference(rel)
+	u.RawQuery = params.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("User-Agent", c.userAgent)
 
 	return req, nil
 }
@@ -95,24 +179,166 @@ func (c *Client) NewRequest(urlStr string,
 // XML decoded and stored in the value pointed to by v, or returned as an error
 // if an API error has occured. If v implements the io.Writer interface, the
 // raw response will be written to v, without attempting to first decode it.
+//
+// The response body has been read and closed by the time Do returns. Errors
+// caused by the response carry the start of the body instead; see
+// StatusError, ContentTypeError and DecodeError.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.client.Do(req)
+	endpoint := strings.TrimPrefix(req.URL.Path, c.baseURL.Path)
+	return c.do(req, v, endpoint, xmlCodec)
+}
+
+// codec describes how response bodies in one format are recognised and
+// decoded.
+type codec struct {
+	accepts func(contentType string) bool
+	decode  func(r io.Reader, v interface{}) error
+}
+
+var xmlCodec = codec{
+	accepts: isXML,
+	decode: func(r io.Reader, v interface{}) error {
+		return xml.NewDecoder(r).Decode(v)
+	},
+}
+
+// do implements Do for any response format. endpoint selects the response
+// size limit.
+func (c *Client) do(req *http.Request, v interface{}, endpoint string,
+	cdc codec) (*http.Response, error) {
+	resp, err := c.send(req)
 	if err != nil {
 		return nil, err
 	}
+	defer drainAndClose(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, ErrTooManyRequests
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp, &StatusError{Response: resp, Snippet: readSnippet(resp.Body)}
+	}
 
-	switch resp.StatusCode {
-	case http.StatusTooManyRequests, http.StatusUnauthorized:
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+	body := c.limitBody(endpoint, resp.Body)
+	if w, ok := v.(io.Writer); ok {
+		_, err = io.Copy(w, body)
+		if body.exceeded {
+			return resp, body.err()
+		}
+		return resp, err
 	}
 
-	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !cdc.accepts(ct) {
+		return resp, &ContentTypeError{ContentType: ct, Snippet: readSnippet(resp.Body)}
+	}
 	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = xml.NewDecoder(resp.Body).Decode(v)
+		var snip snippetWriter
+		err = cdc.decode(io.TeeReader(body, &snip), v)
+		if body.exceeded {
+			return resp, body.err()
+		}
+		if err != nil {
+			return resp, &DecodeError{Err: err, Snippet: snip.String()}
 		}
 	}
-	return resp, err
+	return resp, nil
+}
+
+// limitBody wraps body in a reader that fails once the size limit for
+// endpoint is exceeded.
+func (c *Client) limitBody(endpoint string, body io.Reader) *limitedReader {
+	limit, ok := c.endpointLimits[endpoint]
+	if !ok {
+		limit = c.maxResponseSize
+	}
+	return &limitedReader{r: body, endpoint: endpoint, limit: limit, remaining: limit}
+}
+
+// send performs req, waiting for the rate limit before every attempt and
+// retrying transport errors, rate limiting and server errors as configured.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Do(req)
+		if attempt >= c.maxRetries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		backoff := c.retryWait << uint(attempt)
+		if resp != nil {
+			if after := retryAfter(resp); after > backoff {
+				backoff = after
+			}
+			drainAndClose(resp.Body)
+		}
+		c.logf("pin: retrying %s in %s (attempt %d of %d)", req.URL.Path,
+			backoff, attempt+1, c.maxRetries)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// wait blocks until the rate limit allows another request to be sent.
+func (c *Client) wait(req *http.Request) error {
+	if c.rateInterval <= 0 {
+		return nil
+	}
+
+	c.rateMu.Lock()
+	now := time.Now()
+	at := c.nextRequest
+	if at.Before(now) {
+		at = now
+	}
+	c.nextRequest = at.Add(c.rateInterval)
+	c.rateMu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		select {
+		case <-req.Context().Done():
+			return req.Context().Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
+// drainAndClose discards a bounded amount of what is left of body before
+// closing it, which lets the transport reuse the connection.
+func drainAndClose(body io.ReadCloser) {
+	io.CopyN(ioutil.Discard, body, maxDrain)
+	body.Close()
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns the delay requested by a Retry-After header given in
+// seconds, or zero if there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
 }
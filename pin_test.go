@@ -1,8 +1,14 @@
 package pin
 
 import (
+	"bytes"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"errors"
 	"fmt"
@@ -41,3 +47,296 @@ func TestClientRateLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestNewRequestDoesNotMutateParams(t *testing.T) {
+	params := &url.Values{"url": {"http://example.org"}}
+
+	for i := 0; i < 2; i++ {
+		req, err := client.NewRequest("posts/delete", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.URL.Query()["auth_token"]; len(got) != 1 {
+			t.Errorf("Expected a single auth_token got %v", got)
+		}
+	}
+
+	if _, ok := (*params)["auth_token"]; ok {
+		t.Error("NewRequest added auth_token to the caller's params")
+	}
+}
+
+func TestNewRequestUserAgent(t *testing.T) {
+	c, err := New(WithUserAgent("test/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.NewRequest("endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua := req.Header.Get("User-Agent"); ua != "test/1.0" {
+		t.Errorf("Wrong User-Agent expected 'test/1.0' got '%s'", ua)
+	}
+}
+
+func TestNewBaseURL(t *testing.T) {
+	c, err := New(WithBaseURL("http://localhost:8080/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.NewRequest("posts/update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != "http://localhost:8080/v1/posts/update" {
+		t.Errorf("Wrong request URL got %s", req.URL)
+	}
+
+	c.BaseURL().Path = "/changed/"
+	if c.BaseURL().Path != "/v1/" {
+		t.Error("BaseURL returned a mutable reference to the client's config")
+	}
+
+	if _, err := New(WithBaseURL("/relative")); err == nil {
+		t.Error("Expected error for relative base URL")
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Each call needs its own body, so build a fresh response per request.
+	respond := func(fixture string) httpmock.Responder {
+		body := readFixture(fixture)
+		return func(*http.Request) (*http.Response, error) {
+			return httpmock.NewStringResponse(200, body), nil
+		}
+	}
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/recent?auth_token=user%3Atoken&count=5",
+		respond("posts_recent"))
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/tags/get?auth_token=user%3Atoken",
+		respond("tags_get"))
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/delete?auth_token=user%3Atoken&url=http%3A%2F%2Fexample.org",
+		respond("ok"))
+
+	params := &url.Values{"url": {"http://example.org"}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Posts.Recent(nil, 5)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Tags.Get()
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			req, err := client.NewRequest("posts/delete", params)
+			if err == nil {
+				_, err = client.Do(req, nil)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+var newOptionErrs = []struct {
+	name string
+	opt  Option
+}{
+	{"relative base URL", WithBaseURL("v1")},
+	{"empty user agent", WithUserAgent("")},
+	{"negative timeout", WithTimeout(-time.Second)},
+	{"negative retries", WithRetry(-1, time.Second)},
+	{"negative retry wait", WithRetry(1, -time.Second)},
+	{"negative rate limit", WithRateLimit(-time.Second)},
+}
+
+func TestNewOptionErrors(t *testing.T) {
+	for _, tt := range newOptionErrs {
+		if _, err := New(tt.opt); err == nil {
+			t.Errorf("Expected error for %s", tt.name)
+		}
+	}
+}
+
+func TestNewTimeout(t *testing.T) {
+	c, err := New(WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.client.Timeout != time.Second {
+		t.Errorf("Wrong timeout expected 1s got %s", c.client.Timeout)
+	}
+	if http.DefaultClient.Timeout != 0 {
+		t.Error("WithTimeout modified http.DefaultClient")
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	calls := 0
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/endpoint?auth_token=user%3Atoken",
+		func(*http.Request) (*http.Response, error) {
+			calls++
+			if calls < 3 {
+				return httpmock.NewStringResponse(http.StatusServiceUnavailable, ""), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, readFixture("ok")), nil
+		})
+
+	var logged bytes.Buffer
+	c, err := New(WithAuthToken(&token), WithRetry(2, time.Millisecond),
+		WithLogger(log.New(&logged, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := c.NewRequest("endpoint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(req, nil); err != nil {
+		t.Error(err)
+	}
+	if calls != 3 {
+		t.Errorf("Wrong number of attempts expected 3 got %d", calls)
+	}
+	if n := strings.Count(logged.String(), "retrying"); n != 2 {
+		t.Errorf("Expected 2 retries logged got %d", n)
+	}
+	if strings.Contains(logged.String(), "token") {
+		t.Error("Auth token leaked into the log")
+	}
+}
+
+func TestClientRateLimitInterval(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/endpoint?auth_token=user%3Atoken",
+		func(*http.Request) (*http.Response, error) {
+			return httpmock.NewStringResponse(http.StatusOK, readFixture("ok")), nil
+		})
+
+	c, err := New(WithAuthToken(&token), WithRateLimit(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req, err := c.NewRequest("endpoint", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Do(req, nil); err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Requests were not spaced out, took %s", elapsed)
+	}
+}
+
+func TestDoResponseTooLarge(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/tags/get?auth_token=user%3Atoken",
+		httpmock.NewStringResponder(200, readFixture("tags_get")))
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/all?auth_token=user%3Atoken",
+		httpmock.NewStringResponder(200, readFixture("posts_all")))
+
+	c, err := New(WithAuthToken(&token), WithMaxResponseSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = c.Tags.Get()
+	lerr, ok := err.(*ResponseTooLargeError)
+	if !ok {
+		t.Fatalf("Expected *ResponseTooLargeError got %T (%v)", err, err)
+	}
+	if lerr.Endpoint != "tags/get" || lerr.Limit != 100 {
+		t.Errorf("Wrong error details %+v", lerr)
+	}
+
+	// posts/all has a higher default ceiling of its own.
+	if _, _, err := c.Posts.All(nil, 0, 0, nil, nil); err != nil {
+		t.Error(err)
+	}
+
+	c, err = New(WithAuthToken(&token), WithEndpointMaxResponseSize("posts/all", 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Posts.All(nil, 0, 0, nil, nil); err == nil {
+		t.Error("Expected posts/all limit to be enforced")
+	}
+}
+
+// trackingBody records whether it was read to the end and closed.
+type trackingBody struct {
+	r      *strings.Reader
+	eof    bool
+	closed bool
+}
+
+func (b *trackingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestDoDrainsBodyOnError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	for _, code := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusBadGateway} {
+		body := &trackingBody{r: strings.NewReader(readFixture("posts_err"))}
+		httpmock.Reset()
+		httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/endpoint?auth_token=user%3Atoken",
+			func(*http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: code, Body: body, Header: http.Header{}}, nil
+			})
+
+		req, err := client.NewRequest("endpoint", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Do(req, nil); err == nil {
+			t.Errorf("Expected error for status %d", code)
+		}
+		if !body.eof || !body.closed {
+			t.Errorf("Body not drained and closed for status %d", code)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package pin
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -32,26 +31,69 @@ type Post struct {
 	URL         string
 	Tags        []string
 	ToRead      bool
+	Shared      bool
 	Time        *time.Time
+	Author      string // only set for posts read from feeds
 }
 
-func newPostFromPostResp(presp *postResp) *Post {
-	var toRead bool
-	if presp.ToRead == "yes" {
-		toRead = true
+func newPostFromPostResp(presp *postResp) (*Post, error) {
+	toRead, err := parseYesNo("toread", presp.ToRead)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := parseYesNo("shared", presp.Shared)
+	if err != nil {
+		return nil, err
 	}
 
-	dt, _ := time.Parse(timeLayoutFull, presp.Time)
+	dt, err := time.Parse(timeLayoutFull, presp.Time)
+	if err != nil {
+		return nil, &FieldError{Field: "time", Value: presp.Time, Err: err}
+	}
 
 	return &Post{
 		Title:       presp.Title,
 		Description: presp.Description,
 		Hash:        presp.Hash,
 		URL:         presp.URL,
-		Tags:        strings.Split(presp.Tag, " "),
+		Tags:        strings.Fields(presp.Tag),
 		ToRead:      toRead,
+		Shared:      shared,
 		Time:        &dt,
+	}, nil
+}
+
+// parseYesNo parses the yes/no flags of a post. A missing flag is false.
+func parseYesNo(field, value string) (bool, error) {
+	switch value {
+	case "yes":
+		return true, nil
+	case "no", "":
+		return false, nil
 	}
+	return false, &FieldError{Field: field, Value: value}
+}
+
+// formatYesNo formats a flag the way the API expects it.
+func formatYesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// newPostsFromPostResps converts a list of post responses, failing on the
+// first invalid one.
+func newPostsFromPostResps(presps []*postResp) ([]*Post, error) {
+	posts := make([]*Post, len(presps))
+	for i, v := range presps {
+		p, err := newPostFromPostResp(v)
+		if err != nil {
+			return nil, err
+		}
+		posts[i] = p
+	}
+	return posts, nil
 }
 
 type postResp struct {
@@ -61,6 +103,7 @@ type postResp struct {
 	URL         string `xml:"href,attr"`
 	Tag         string `xml:"tag,attr"`
 	ToRead      string `xml:"toread,attr"`
+	Shared      string `xml:"shared,attr"`
 	Time        string `xml:"time,attr"`
 }
 
@@ -73,11 +116,11 @@ type Date struct {
 func newDateFromPostResp(dresp *dateResp) (*Date, error) {
 	dt, err := time.Parse(timeLayoutShort, dresp.Date)
 	if err != nil {
-		return nil, err
+		return nil, &FieldError{Field: "date", Value: dresp.Date, Err: err}
 	}
 	c, err := strconv.Atoi(dresp.Count)
 	if err != nil {
-		return nil, err
+		return nil, &FieldError{Field: "count", Value: dresp.Count, Err: err}
 	}
 
 	return &Date{
@@ -92,12 +135,44 @@ type dateResp struct {
 }
 
 // Add creates a new Post for the authenticated account. urlStr and title are
-// required.
+// required. Before the request is sent, the post is passed through the
+// functions set with WithPreAdd and its tags are checked against the tag
+// policy, if any; see WithTagPolicy. If Pinboard does not add the post, the
+// error is a *ResultError holding its result code.
 //
 // https://pinboard.in/api/#posts_add
 func (s *PostsService) Add(urlStr, title, description string, tags []string,
 	creationTime *time.Time, replace, shared,
 	toread bool) (*http.Response, error) {
+	if len(s.client.preAdd) > 0 {
+		p := &Post{
+			URL:         urlStr,
+			Title:       title,
+			Description: description,
+			Tags:        append([]string(nil), tags...),
+			Time:        creationTime,
+			Shared:      shared,
+			ToRead:      toread,
+		}
+		for _, f := range s.client.preAdd {
+			if err := f(p); err != nil {
+				return nil, err
+			}
+		}
+		urlStr, title, description, tags = p.URL, p.Title, p.Description, p.Tags
+		creationTime, shared, toread = p.Time, p.Shared, p.ToRead
+	}
+
+	if p := s.client.tagPolicy; p != nil {
+		var err error
+		if tags, err = p.apply(tags); err != nil {
+			if p.mode == PolicyReject {
+				return nil, err
+			}
+			s.client.logf("%v (%s)", err, urlStr)
+		}
+	}
+
 	var strTime string
 	if creationTime != nil {
 		strTime = creationTime.Format(timeLayoutFull)
@@ -107,11 +182,11 @@ func (s *PostsService) Add(urlStr, title, description string, tags []string,
 		"url":         {urlStr},
 		"description": {title},
 		"extended":    {description},
-		"tags":        tags,
+		"tags":        {strings.Join(tags, " ")},
 		"dt":          {strTime},
-		"replace":     {fmt.Sprintf("%t", replace)},
-		"shared":      {fmt.Sprintf("%t", shared)},
-		"toread":      {fmt.Sprintf("%t", toread)},
+		"replace":     {formatYesNo(replace)},
+		"shared":      {formatYesNo(shared)},
+		"toread":      {formatYesNo(toread)},
 	}
 
 	req, err := s.client.NewRequest("posts/add", params)
@@ -119,14 +194,27 @@ func (s *PostsService) Add(urlStr, title, description string, tags []string,
 		return nil, err
 	}
 
-	resp, err := s.client.Do(req, nil)
+	var result resultResp
+	resp, err := s.client.Do(req, &result)
 	if err != nil {
 		return resp, err
 	}
+	if result.Code != resultDone {
+		return resp, &ResultError{Endpoint: "posts/add", Code: result.Code}
+	}
 
 	return resp, nil
 }
 
+// SavePost adds p through posts, keeping its title, description, tags, time
+// and flags. It is the way to write back a Post that was read earlier. If
+// replace is false and the URL is already bookmarked, Pinboard keeps the
+// existing post.
+func SavePost(posts PostsAPI, p *Post, replace bool) (*http.Response, error) {
+	return posts.Add(p.URL, p.Title, p.Description, p.Tags, p.Time, replace,
+		p.Shared, p.ToRead)
+}
+
 // Delete deletes the specified Post from the authenticated account where
 // urlStr is the URL of the Post to delete.
 //
@@ -182,9 +270,9 @@ func (s *PostsService) Get(tags []string, creationTime *time.Time, urlStr string
 		return nil, resp, err
 	}
 
-	posts := make([]*Post, len(result.Posts))
-	for i, v := range result.Posts {
-		posts[i] = newPostFromPostResp(v)
+	posts, err := newPostsFromPostResps(result.Posts)
+	if err != nil {
+		return nil, resp, err
 	}
 
 	return posts, resp, nil
@@ -211,7 +299,7 @@ func (s *PostsService) LastTimeUpdated() (*time.Time, *http.Response, error) {
 
 	updated, err := time.Parse(timeLayoutFull, result.Time)
 	if err != nil {
-		return nil, resp, err
+		return nil, resp, &FieldError{Field: "time", Value: result.Time, Err: err}
 	}
 
 	return &updated, resp, nil
@@ -247,7 +335,7 @@ func (s *PostsService) Dates(tags []string) ([]*Date, *http.Response, error) {
 	for i, v := range result.Dates {
 		d, err := newDateFromPostResp(v)
 		if err != nil {
-			return nil, nil, err
+			return nil, resp, err
 		}
 		dates[i] = d
 	}
@@ -290,9 +378,9 @@ func (s *PostsService) Recent(tags []string, count int) ([]*Post,
 		return nil, resp, err
 	}
 
-	posts := make([]*Post, len(result.Posts))
-	for i, v := range result.Posts {
-		posts[i] = newPostFromPostResp(v)
+	posts, err := newPostsFromPostResps(result.Posts)
+	if err != nil {
+		return nil, resp, err
 	}
 
 	return posts, resp, nil
@@ -342,9 +430,9 @@ func (s *PostsService) All(tags []string, start int, results int, fromdt, todt *
 		return nil, resp, err
 	}
 
-	posts := make([]*Post, len(result.Posts))
-	for i, v := range result.Posts {
-		posts[i] = newPostFromPostResp(v)
+	posts, err := newPostsFromPostResps(result.Posts)
+	if err != nil {
+		return nil, resp, err
 	}
 
 	return posts, resp, nil
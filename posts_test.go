@@ -1,6 +1,7 @@
 package pin
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -18,7 +19,7 @@ func TestPostsAdd(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
 
-	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/add?auth_token=user%3Atoken&description=Title&dt=2009-11-10T23%3A00%3A00Z&extended=Description&replace=true&shared=true&tags=one&tags=two&tags=three&tags=four&toread=true&url=http%3A%2F%2Fexample.org",
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/add?auth_token=user%3Atoken&description=Title&dt=2009-11-10T23%3A00%3A00Z&extended=Description&replace=yes&shared=yes&tags=one+two+three+four&toread=yes&url=http%3A%2F%2Fexample.org",
 		httpmock.NewStringResponder(200, readFixture("ok")))
 
 	tags := []string{"one", "two", "three", "four"}
@@ -28,6 +29,60 @@ func TestPostsAdd(t *testing.T) {
 	}
 }
 
+func TestPostsAddResultCode(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterNoResponder(httpmock.NewStringResponder(200, readFixture("posts_err")))
+
+	_, err := client.Posts.Add("http://example.org", "Title", "", nil, nil, false, false, false)
+	rerr, ok := err.(*ResultError)
+	if !ok {
+		t.Fatalf("Expected *ResultError got %v", err)
+	}
+	if rerr.Code != "something went wrong" {
+		t.Errorf("Wrong result code %q", rerr.Code)
+	}
+}
+
+func TestPostsAddPreAdd(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/add?auth_token=user%3Atoken&description=Title&dt=&extended=&replace=no&shared=no&tags=one+auto&toread=yes&url=http%3A%2F%2Fexample.org",
+		httpmock.NewStringResponder(200, readFixture("ok")))
+
+	tags := []string{"one"}
+	c, err := New(WithAuthToken(&token),
+		WithPreAdd(func(p *Post) error {
+			p.Tags = append(p.Tags, "auto")
+			return nil
+		}),
+		WithPreAdd(func(p *Post) error {
+			p.ToRead = true
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Posts.Add("http://example.org", "Title", "", tags, nil, false, false, false); err != nil {
+		t.Error(err)
+	}
+	if len(tags) != 1 {
+		t.Error("Caller's tags were modified")
+	}
+
+	c, err = New(WithAuthToken(&token), WithPreAdd(func(p *Post) error {
+		return errors.New("blocked")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Posts.Add("http://example.org", "Title", "", nil, nil, false, false, false); err == nil || err.Error() != "blocked" {
+		t.Errorf("Expected pre-add error got %v", err)
+	}
+}
+
 func TestPostsDelete(t *testing.T) {
 	httpmock.Activate()
 	defer httpmock.DeactivateAndReset()
@@ -55,7 +110,7 @@ func TestPostsGet(t *testing.T) {
 	}
 
 	if len(posts) != 1 {
-		t.Errorf("Retrieved wrong amount - expected 1 got %s", len(posts))
+		t.Errorf("Retrieved wrong amount - expected 1 got %d", len(posts))
 	}
 
 	if strings.Compare(posts[0].URL, "http://www.howtocreate.co.uk/tutorials/texterise.php?dom=1") != 0 {
@@ -140,12 +195,16 @@ func TestPostsAll(t *testing.T) {
 	}
 
 	if len(posts) != 2 {
-		t.Errorf("Retrieved wrong amount - expected 2 got %s", len(posts))
+		t.Errorf("Retrieved wrong amount - expected 2 got %d", len(posts))
 	}
 
 	if strings.Compare(posts[0].URL, "http://www.weather.com/") != 0 {
 		t.Errorf("Retrieved wrong results (%s)", posts[0].URL)
 	}
+
+	if posts[0].Shared || !posts[1].Shared {
+		t.Errorf("Retrieved wrong shared flags (%t, %t)", posts[0].Shared, posts[1].Shared)
+	}
 }
 
 var postsAllUrlTests = []struct {
@@ -190,7 +249,7 @@ func TestPostsUpdate(t *testing.T) {
 	}
 
 	if upd.Format(timeLayoutFull) != "2011-03-24T19:02:07Z" {
-		t.Error("Wrong time recieved, expected 2011-03-24T19:02:07Z got %s", upd.Format(timeLayoutFull))
+		t.Errorf("Wrong time recieved, expected 2011-03-24T19:02:07Z got %s", upd.Format(timeLayoutFull))
 	}
 }
 
@@ -234,3 +293,29 @@ func TestPostsSuggest(t *testing.T) {
 		t.Errorf("Retrieved wrong amount of popular tags - recommended 10 got %d", len(recommended))
 	}
 }
+
+func TestSavePost(t *testing.T) {
+	posts := &FakePostsService{}
+	p := &Post{
+		URL:         "http://example.org",
+		Title:       "Title",
+		Description: "Description",
+		Tags:        []string{"one", "two"},
+		Time:        &time1,
+		Shared:      true,
+		ToRead:      true,
+	}
+	if _, err := SavePost(posts, p, true); err != nil {
+		t.Error(err)
+	}
+
+	calls := posts.AddCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 call to Add got %d", len(calls))
+	}
+	c := calls[0]
+	if c.URL != p.URL || c.Title != p.Title || c.Description != p.Description ||
+		len(c.Tags) != 2 || c.CreationTime != p.Time || !c.Replace || !c.Shared || !c.ToRead {
+		t.Errorf("Post not saved as is: %+v", c)
+	}
+}
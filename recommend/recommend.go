@@ -0,0 +1,262 @@
+// Package recommend suggests tags for new bookmarks from the way existing
+// ones are tagged. It complements PostsService.Suggest, which often has
+// nothing to offer for URLs Pinboard has not seen before.
+package recommend
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/search"
+)
+
+// Source records where a suggestion came from.
+type Source int
+
+const (
+	FromHost         Source = 1 << iota // tags of posts from the same host
+	FromText                            // tags of posts with similar words
+	FromCooccurrence                    // tags used together with the above
+	FromPopular                         // popular tags from PostsService.Suggest
+	FromRecommended                     // recommended tags from PostsService.Suggest
+)
+
+var sourceNames = []string{"host", "text", "cooccurrence", "popular", "recommended"}
+
+func (s Source) String() string {
+	var names []string
+	for i, name := range sourceNames {
+		if s&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Weights of the signals in the combined score. Each local signal scores a
+// tag between 0 and 1 before weighting; remote suggestions keep the score
+// given by pin.MergeSuggestions.
+const (
+	hostWeight    = 1.0
+	textWeight    = 1.0
+	cooccurWeight = 0.5
+	remoteWeight  = 0.5
+)
+
+// Suggestion is a scored tag. Tags are lower case.
+type Suggestion struct {
+	Tag     string
+	Score   float64
+	Sources Source
+}
+
+// Model holds the tag statistics of a set of posts. It is not changed after
+// Train returns, so it is safe for concurrent use.
+type Model struct {
+	docs     int
+	tagPosts map[string]int
+	cooccur  map[string]map[string]int
+
+	hostPosts map[string]int
+	hostTags  map[string]map[string]int
+
+	termPosts map[string]int
+	termTags  map[string]map[string]int
+}
+
+// Train builds a model from posts. Tags are compared case-insensitively.
+func Train(posts []*pin.Post) *Model {
+	m := &Model{
+		docs:      len(posts),
+		tagPosts:  make(map[string]int),
+		cooccur:   make(map[string]map[string]int),
+		hostPosts: make(map[string]int),
+		hostTags:  make(map[string]map[string]int),
+		termPosts: make(map[string]int),
+		termTags:  make(map[string]map[string]int),
+	}
+	for _, p := range posts {
+		tags := normalize(p.Tags)
+		for _, t := range tags {
+			m.tagPosts[t]++
+			for _, u := range tags {
+				if u != t {
+					inc(m.cooccur, t, u)
+				}
+			}
+		}
+
+		if h := host(p.URL); h != "" {
+			m.hostPosts[h]++
+			for _, t := range tags {
+				inc(m.hostTags, h, t)
+			}
+		}
+
+		for term := range terms(p.Title + " " + p.Description) {
+			m.termPosts[term]++
+			for _, t := range tags {
+				inc(m.termTags, term, t)
+			}
+		}
+	}
+	return m
+}
+
+func inc(m map[string]map[string]int, k, t string) {
+	if m[k] == nil {
+		m[k] = make(map[string]int)
+	}
+	m[k][t]++
+}
+
+// normalize lower cases tags and drops duplicates and empty ones.
+func normalize(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func host(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// terms returns the distinct terms of s, leaving out single characters.
+func terms(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range search.Tokenize(s) {
+		if len(t) > 1 {
+			set[t] = true
+		}
+	}
+	return set
+}
+
+// Suggest returns up to limit tags for a bookmark of urlStr titled title,
+// best first. A limit of zero or less returns every candidate.
+func (m *Model) Suggest(urlStr, title string, limit int) []Suggestion {
+	scores := make(map[string]*Suggestion)
+	add := func(tag string, score float64, src Source) {
+		s := scores[tag]
+		if s == nil {
+			s = &Suggestion{Tag: tag}
+			scores[tag] = s
+		}
+		s.Score += score
+		s.Sources |= src
+	}
+
+	if h := host(urlStr); m.hostPosts[h] > 0 {
+		for t, n := range m.hostTags[h] {
+			add(t, hostWeight*float64(n)/float64(m.hostPosts[h]), FromHost)
+		}
+	}
+
+	// Each term votes for the tags of the posts it occurs in, in proportion
+	// to how often they carry the tag, weighted by its inverse document
+	// frequency. The votes are normalised by the total weight.
+	var total float64
+	text := make(map[string]float64)
+	for term := range terms(title) {
+		df := m.termPosts[term]
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(m.docs)/float64(df))
+		total += idf
+		for t, n := range m.termTags[term] {
+			text[t] += idf * float64(n) / float64(df)
+		}
+	}
+	for t, v := range text {
+		add(t, textWeight*v/total, FromText)
+	}
+
+	// Tags found so far pull in the tags they are commonly used with.
+	seeds := make(map[string]float64, len(scores))
+	for t, s := range scores {
+		seeds[t] = s.Score
+	}
+	for seed, score := range seeds {
+		for t, n := range m.cooccur[seed] {
+			add(t, cooccurWeight*score*float64(n)/float64(m.tagPosts[seed]), FromCooccurrence)
+		}
+	}
+
+	return rank(scores, limit)
+}
+
+// Merge combines local suggestions with remote, the merged results of
+// PostsService.Suggest; see pin.MergeSuggestions. Scores of the same tag are
+// added up.
+func Merge(local []Suggestion, remote pin.Suggestions, limit int) []Suggestion {
+	scores := make(map[string]*Suggestion)
+	add := func(tag string, score float64, src Source) {
+		s := scores[tag]
+		if s == nil {
+			s = &Suggestion{Tag: tag}
+			scores[tag] = s
+		}
+		s.Score += score
+		s.Sources |= src
+	}
+
+	for _, s := range local {
+		add(s.Tag, s.Score, s.Sources)
+	}
+	for _, sg := range remote {
+		var src Source
+		if sg.Popular {
+			src |= FromPopular
+		}
+		if sg.Recommended {
+			src |= FromRecommended
+		}
+		add(sg.Tag, remoteWeight*float64(sg.Score), src)
+	}
+	return rank(scores, limit)
+}
+
+// SuggestWith returns the suggestions of the model merged with those of
+// posts.Suggest for urlStr.
+func (m *Model) SuggestWith(posts pin.PostsAPI, urlStr, title string,
+	limit int) ([]Suggestion, error) {
+	popular, recommended, _, err := posts.Suggest(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(m.Suggest(urlStr, title, 0), pin.MergeSuggestions(popular, recommended),
+		limit), nil
+}
+
+// rank orders suggestions by score, then by tag, and keeps the first limit.
+func rank(scores map[string]*Suggestion, limit int) []Suggestion {
+	out := make([]Suggestion, 0, len(scores))
+	for _, s := range scores {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Tag < out[j].Tag
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package recommend
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/zachlatta/pin"
+)
+
+var posts = []*pin.Post{
+	{URL: "https://github.com/golang/go", Title: "The Go programming language", Tags: []string{"Go", "code"}},
+	{URL: "https://github.com/rust-lang/rust", Title: "Rust language", Tags: []string{"rust", "code"}},
+	{URL: "https://blog.golang.org/errors", Title: "Error handling in Go", Tags: []string{"go", "errors"}},
+	{URL: "https://www.postgresql.org/docs/", Title: "PostgreSQL documentation", Tags: []string{"databases", "docs"}},
+	{URL: "https://sqlite.org/lang.html", Title: "SQL as understood by SQLite", Tags: []string{"databases", "sql"}},
+}
+
+func tags(ss []Suggestion) []string {
+	var out []string
+	for _, s := range ss {
+		out = append(out, s.Tag)
+	}
+	return out
+}
+
+func TestSuggestHost(t *testing.T) {
+	m := Train(posts)
+	got := m.Suggest("https://github.com/zachlatta/pin", "pin", 1)
+	if len(got) != 1 || got[0].Tag != "code" || got[0].Sources&FromHost == 0 {
+		t.Errorf("Expected code from host got %+v", got)
+	}
+}
+
+func TestSuggestText(t *testing.T) {
+	m := Train(posts)
+	got := m.Suggest("https://example.com/new", "Generics in Go", 0)
+	if len(got) == 0 || got[0].Tag != "go" || got[0].Sources&FromText == 0 {
+		t.Fatalf("Expected go from text got %+v", got)
+	}
+
+	var cooccur bool
+	for _, s := range got {
+		if s.Tag == "code" || s.Tag == "errors" {
+			cooccur = cooccur || s.Sources&FromCooccurrence != 0
+		}
+	}
+	if !cooccur {
+		t.Errorf("Expected tags used with go to be suggested got %v", tags(got))
+	}
+
+	if got := m.Suggest("https://example.com/new", "something else entirely", 0); len(got) != 0 {
+		t.Errorf("Expected no suggestions got %v", tags(got))
+	}
+}
+
+func TestMerge(t *testing.T) {
+	local := []Suggestion{{Tag: "go", Score: 0.8, Sources: FromText}}
+	remote := pin.MergeSuggestions([]string{"Golang", "GO"}, []string{"go", "Tools", "tools"})
+	got := Merge(local, remote, 0)
+
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 suggestions got %+v", got)
+	}
+	if got[0].Tag != "go" || got[0].Sources != FromText|FromPopular|FromRecommended {
+		t.Errorf("Wrong first suggestion %+v", got[0])
+	}
+	if got[0].Score != 0.8+0.5+1.0 {
+		t.Errorf("Wrong score %v", got[0].Score)
+	}
+	if got[1].Tag != "tools" || got[2].Tag != "golang" {
+		t.Errorf("Wrong order %v", tags(got))
+	}
+	if s := got[0].Sources.String(); s != "text|popular|recommended" {
+		t.Errorf("Wrong sources %q", s)
+	}
+}
+
+func TestSuggestWith(t *testing.T) {
+	fake := &pin.FakePostsService{
+		SuggestStub: func(string) ([]string, []string, *http.Response, error) {
+			return []string{"github"}, []string{"Code"}, nil, nil
+		},
+	}
+	got, err := Train(posts).SuggestWith(fake, "https://github.com/x", "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || got[0].Tag != "code" || got[1].Tag != "go" || got[2].Tag != "rust" ||
+		got[3].Tag != "github" {
+		t.Errorf("Wrong suggestions %+v", got)
+	}
+
+	fake.SuggestStub = func(string) ([]string, []string, *http.Response, error) {
+		return nil, nil, nil, errors.New("boom")
+	}
+	if _, err := Train(posts).SuggestWith(fake, "https://github.com/x", "", 2); err == nil {
+		t.Error("Expected error from Suggest")
+	}
+}
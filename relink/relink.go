@@ -0,0 +1,289 @@
+// Package relink keeps bookmarked URLs current. It follows permanent
+// redirects, such as those of moved pages and short links, detects http://
+// links that are also served over HTTPS, and rewrites the affected bookmarks.
+package relink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// DefaultMaxRedirects is the longest redirect chain followed by a zero
+// Resolver.
+const DefaultMaxRedirects = 10
+
+// Reason explains why a rewrite was proposed.
+type Reason int
+
+const (
+	// Moved means the URL permanently redirects elsewhere.
+	Moved Reason = 1 << iota
+
+	// Upgraded means the URL is also served over HTTPS.
+	Upgraded
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Moved:
+		return "moved"
+	case Upgraded:
+		return "https"
+	case Moved | Upgraded:
+		return "moved, https"
+	}
+	return "none"
+}
+
+// Hop is one response in a redirect chain.
+type Hop struct {
+	URL        string
+	StatusCode int
+}
+
+// Rewrite proposes replacing the URL of Post with NewURL.
+type Rewrite struct {
+	Post   *pin.Post
+	NewURL string
+	Reason Reason
+	Chain  []Hop // the responses seen from Post.URL to NewURL
+}
+
+// Conflict is a rewrite that was left out of a plan because its new URL is
+// already bookmarked, or is the new URL of an earlier rewrite in the plan.
+// Existing is the bookmark that has, or will get, the URL.
+type Conflict struct {
+	Rewrite  *Rewrite
+	Existing *pin.Post
+}
+
+// PostError records a post whose URL could not be resolved.
+type PostError struct {
+	Post *pin.Post
+	Err  error
+}
+
+// Plan lists the rewrites for a set of posts. Review it, then pass it to
+// Apply.
+type Plan struct {
+	Rewrites  []*Rewrite
+	Conflicts []Conflict
+	Errors    []PostError
+}
+
+// Resolver works out the current URL of bookmarks.
+type Resolver struct {
+	// Client sends the requests. Redirects are followed by the Resolver
+	// itself, so any CheckRedirect is ignored. If nil, a client with a 15
+	// second timeout is used.
+	Client *http.Client
+
+	// MaxRedirects bounds the redirect chains followed. Zero means
+	// DefaultMaxRedirects.
+	MaxRedirects int
+
+	// UpgradeHTTPS proposes https:// URLs for http:// ones when the same
+	// resource is served over HTTPS.
+	UpgradeHTTPS bool
+
+	// Delay is the pause between posts when building a plan.
+	Delay time.Duration
+
+	// UserAgent is sent with each request if set.
+	UserAgent string
+}
+
+// Resolve returns the rewrite for p, or nil if its URL is current.
+func (r *Resolver) Resolve(ctx context.Context, p *pin.Post) (*Rewrite, error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("relink: unsupported scheme %q", u.Scheme)
+	}
+
+	client := r.client()
+	target, chain, err := r.follow(ctx, client, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var reason Reason
+	if target.String() != u.String() {
+		reason |= Moved
+	}
+	if r.UpgradeHTTPS && target.Scheme == "http" {
+		secure := *target
+		secure.Scheme = "https"
+		if secure.Host == target.Hostname()+":80" {
+			secure.Host = target.Hostname()
+		}
+		resp, err := r.request(ctx, client, &secure)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			chain = append(chain, Hop{secure.String(), resp.StatusCode})
+			target = &secure
+			reason |= Upgraded
+		}
+	}
+
+	if reason == 0 {
+		return nil, nil
+	}
+	return &Rewrite{Post: p, NewURL: target.String(), Reason: reason, Chain: chain}, nil
+}
+
+// follow follows the permanent redirects from u. It stops at the first
+// temporary redirect, since the URL before it is still the canonical one, and
+// returns u unchanged if the final page does not load successfully.
+func (r *Resolver) follow(ctx context.Context, client *http.Client,
+	u *url.URL) (*url.URL, []Hop, error) {
+	max := r.MaxRedirects
+	if max <= 0 {
+		max = DefaultMaxRedirects
+	}
+
+	var chain []Hop
+	cur := u
+	for i := 0; ; i++ {
+		resp, err := r.request(ctx, client, cur)
+		if err != nil {
+			return nil, chain, err
+		}
+		chain = append(chain, Hop{cur.String(), resp.StatusCode})
+
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+				return cur, chain, nil
+			}
+			return u, chain, nil
+		}
+
+		if i >= max {
+			return nil, chain, errors.New("relink: too many redirects")
+		}
+		loc, err := resp.Location()
+		if err != nil {
+			return nil, chain, err
+		}
+		cur = loc
+	}
+}
+
+// request sends a HEAD request for u, falling back to GET for servers that
+// reject HEAD, and closes the response body.
+func (r *Resolver) request(ctx context.Context, client *http.Client,
+	u *url.URL) (*http.Response, error) {
+	resp, err := r.send(ctx, client, "HEAD", u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = r.send(ctx, client, "GET", u)
+	}
+	return resp, err
+}
+
+func (r *Resolver) send(ctx context.Context, client *http.Client, method string,
+	u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if r.UserAgent != "" {
+		req.Header.Set("User-Agent", r.UserAgent)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.CopyN(ioutil.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+	return resp, nil
+}
+
+func (r *Resolver) client() *http.Client {
+	var client http.Client
+	if r.Client != nil {
+		client = *r.Client
+	} else {
+		client.Timeout = 15 * time.Second
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+// Plan resolves every post and collects the proposed rewrites. A rewrite to a
+// URL that another of the posts already has, or that an earlier rewrite
+// already moves a post to, is reported as a conflict instead, since applying
+// it would overwrite that bookmark. Such posts are duplicates; see the dedupe
+// package to merge them.
+func (r *Resolver) Plan(ctx context.Context, posts []*pin.Post) (*Plan, error) {
+	byURL := make(map[string]*pin.Post, len(posts))
+	for _, p := range posts {
+		byURL[p.URL] = p
+	}
+	planned := make(map[string]*pin.Post)
+
+	plan := &Plan{}
+	for i, p := range posts {
+		if i > 0 && r.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return plan, ctx.Err()
+			case <-time.After(r.Delay):
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return plan, err
+		}
+
+		rw, err := r.Resolve(ctx, p)
+		switch {
+		case err != nil:
+			plan.Errors = append(plan.Errors, PostError{p, err})
+		case rw == nil:
+		case byURL[rw.NewURL] != nil:
+			plan.Conflicts = append(plan.Conflicts, Conflict{rw, byURL[rw.NewURL]})
+		case planned[rw.NewURL] != nil:
+			plan.Conflicts = append(plan.Conflicts, Conflict{rw, planned[rw.NewURL]})
+		default:
+			planned[rw.NewURL] = p
+			plan.Rewrites = append(plan.Rewrites, rw)
+		}
+	}
+	return plan, nil
+}
+
+// Apply carries out the rewrites of plan. Each bookmark is added again under
+// its new URL, keeping its title, description, tags, time and flags, and the
+// old one is deleted only once that succeeded. It stops at the first error
+// and returns the number of rewrites applied. A rewrite whose old bookmark
+// could not be deleted counts as applied, since its new bookmark exists; the
+// error then names both URLs so the duplicate can be removed.
+func Apply(posts pin.PostsAPI, plan *Plan) (int, error) {
+	for i, rw := range plan.Rewrites {
+		p := *rw.Post
+		p.URL = rw.NewURL
+		if _, err := pin.SavePost(posts, &p, true); err != nil {
+			return i, fmt.Errorf("relink: adding %s: %v", rw.NewURL, err)
+		}
+		if _, err := posts.Delete(rw.Post.URL); err != nil {
+			return i + 1, fmt.Errorf("relink: saved %s but did not delete %s: %v",
+				rw.NewURL, rw.Post.URL, err)
+		}
+	}
+	return len(plan.Rewrites), nil
+}
@@ -0,0 +1,193 @@
+package relink
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zachlatta/pin"
+)
+
+// transport serves http:// and https:// requests from separate handlers
+// without touching the network.
+type transport struct {
+	http, https http.Handler
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.http
+	if req.URL.Scheme == "https" {
+		h = t.https
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func newResolver() *Resolver {
+	plain := http.NewServeMux()
+	plain.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {})
+	plain.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	plain.HandleFunc("/temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	plain.HandleFunc("/chain", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/old", http.StatusPermanentRedirect)
+	})
+	plain.HandleFunc("/chain-temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/temp", http.StatusMovedPermanently)
+	})
+	plain.HandleFunc("/moved-dead", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/missing", http.StatusMovedPermanently)
+	})
+	plain.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+	})
+
+	secure := http.NewServeMux()
+	secure.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {})
+
+	return &Resolver{Client: &http.Client{Transport: &transport{plain, secure}}}
+}
+
+func TestResolve(t *testing.T) {
+	r := newResolver()
+
+	tests := []struct {
+		url     string
+		upgrade bool
+		want    string // empty for no rewrite
+		reason  Reason
+	}{
+		{"http://example.org/page", false, "", 0},
+		{"http://example.org/old", false, "http://example.org/page", Moved},
+		{"http://example.org/chain", false, "http://example.org/page", Moved},
+		{"http://example.org/temp", false, "", 0},
+		{"http://example.org/chain-temp", false, "http://example.org/temp", Moved},
+		{"http://example.org/moved-dead", false, "", 0},
+		{"http://example.org/page", true, "https://example.org/page", Upgraded},
+		{"http://example.org/old", true, "https://example.org/page", Moved | Upgraded},
+		{"http://example.org/temp", true, "", 0},
+	}
+	for _, tt := range tests {
+		r.UpgradeHTTPS = tt.upgrade
+		rw, err := r.Resolve(context.Background(), &pin.Post{URL: tt.url})
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if tt.want == "" {
+			if rw != nil {
+				t.Errorf("%s: expected no rewrite got %s", tt.url, rw.NewURL)
+			}
+			continue
+		}
+		if rw == nil {
+			t.Errorf("%s: expected rewrite to %s got none", tt.url, tt.want)
+			continue
+		}
+		if rw.NewURL != tt.want || rw.Reason != tt.reason {
+			t.Errorf("%s: expected %s (%s) got %s (%s)", tt.url, tt.want, tt.reason,
+				rw.NewURL, rw.Reason)
+		}
+	}
+
+	if _, err := r.Resolve(context.Background(), &pin.Post{URL: "http://example.org/loop"}); err == nil {
+		t.Error("Expected error for redirect loop")
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	r := newResolver()
+	moved := &pin.Post{
+		URL:         "http://example.org/old",
+		Title:       "Old",
+		Description: "Moved page",
+		Tags:        []string{"one"},
+		Shared:      true,
+		ToRead:      true,
+	}
+	conflicting := &pin.Post{URL: "http://example.org/chain"}
+	existing := &pin.Post{URL: "http://example.org/page"}
+	broken := &pin.Post{URL: "http://example.org/loop"}
+
+	plan, err := r.Plan(context.Background(), []*pin.Post{moved, conflicting, existing, broken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both moved and conflicting redirect to a URL that is already
+	// bookmarked.
+	if len(plan.Rewrites) != 0 || len(plan.Conflicts) != 2 || len(plan.Errors) != 1 {
+		t.Fatalf("Wrong plan: %d rewrites, %d conflicts, %d errors", len(plan.Rewrites),
+			len(plan.Conflicts), len(plan.Errors))
+	}
+
+	// Without the existing bookmark, moved and conflicting still redirect
+	// to the same new URL, so only the first may be rewritten.
+	plan, err = r.Plan(context.Background(), []*pin.Post{moved, conflicting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Rewrites) != 1 || plan.Rewrites[0].Post != moved || len(plan.Conflicts) != 1 {
+		t.Fatalf("Wrong plan: %d rewrites, %d conflicts", len(plan.Rewrites), len(plan.Conflicts))
+	}
+	if c := plan.Conflicts[0]; c.Rewrite.Post != conflicting || c.Existing != moved {
+		t.Errorf("Wrong conflict %+v", c)
+	}
+
+	plan, err = r.Plan(context.Background(), []*pin.Post{moved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := &pin.FakePostsService{}
+	n, err := Apply(posts, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 rewrite applied got %d", n)
+	}
+
+	adds := posts.AddCalls()
+	if len(adds) != 1 {
+		t.Fatalf("Expected 1 add got %d", len(adds))
+	}
+	a := adds[0]
+	if a.URL != "http://example.org/page" || a.Title != "Old" || a.Description != "Moved page" ||
+		len(a.Tags) != 1 || !a.Shared || !a.ToRead {
+		t.Errorf("Bookmark not preserved: %+v", a)
+	}
+	if dels := posts.DeleteCalls(); len(dels) != 1 || dels[0] != "http://example.org/old" {
+		t.Errorf("Wrong deletes %v", dels)
+	}
+}
+
+func TestApplyDeleteError(t *testing.T) {
+	plan := &Plan{Rewrites: []*Rewrite{
+		{Post: &pin.Post{URL: "http://example.org/a"}, NewURL: "https://example.org/a"},
+		{Post: &pin.Post{URL: "http://example.org/b"}, NewURL: "https://example.org/b"},
+	}}
+	posts := &pin.FakePostsService{
+		DeleteStub: func(urlStr string) (*http.Response, error) {
+			return nil, errors.New("boom")
+		},
+	}
+
+	n, err := Apply(posts, plan)
+	if n != 1 {
+		t.Errorf("Expected the half applied rewrite to count got %d", n)
+	}
+	if err == nil || !strings.Contains(err.Error(), "saved https://example.org/a but did not delete http://example.org/a") {
+		t.Errorf("Wrong error %v", err)
+	}
+	if adds := posts.AddCalls(); len(adds) != 1 {
+		t.Errorf("Expected to stop after the first rewrite got %d adds", len(adds))
+	}
+}
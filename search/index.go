@@ -0,0 +1,399 @@
+// Package search provides a local full-text index over bookmarks, since the
+// Pinboard API has no search endpoint of its own.
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/internal/atomicfile"
+	"github.com/zachlatta/pin/watch"
+)
+
+// fieldGap separates the positions of consecutive fields, so that phrases
+// never match across a field boundary.
+const fieldGap = 1000
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Index is an inverted index over the title, description, URL and tags of a
+// set of posts. An Index is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*document        // by URL
+	postings map[string]map[string][]int // term -> URL -> positions
+	totalLen int
+}
+
+type document struct {
+	post   *pin.Post
+	terms  []string // distinct terms, for removal
+	length int
+}
+
+// New returns an empty Index.
+func New() *Index {
+	return &Index{
+		docs:     make(map[string]*document),
+		postings: make(map[string]map[string][]int),
+	}
+}
+
+// Len returns the number of posts in the index.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Add indexes posts, replacing any already indexed under the same URL. Use it
+// with the result of PostsService.Recent to pick up new bookmarks.
+func (ix *Index) Add(posts ...*pin.Post) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, p := range posts {
+		ix.remove(p.URL)
+		ix.add(p)
+	}
+}
+
+// Remove drops the posts with the given URLs from the index.
+func (ix *Index) Remove(urls ...string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, u := range urls {
+		ix.remove(u)
+	}
+}
+
+// Sync makes the index hold exactly posts, which should be the result of
+// PostsService.All.
+func (ix *Index) Sync(posts []*pin.Post) {
+	keep := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		keep[p.URL] = true
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for u := range ix.docs {
+		if !keep[u] {
+			ix.remove(u)
+		}
+	}
+	for _, p := range posts {
+		ix.remove(p.URL)
+		ix.add(p)
+	}
+}
+
+// Apply updates the index from a watch event. It has the signature expected by
+// watch.Watcher.RunFunc.
+func (ix *Index) Apply(e watch.Event) error {
+	if e.Type == watch.Deleted {
+		ix.Remove(e.Post.URL)
+	} else {
+		ix.Add(e.Post)
+	}
+	return nil
+}
+
+func (ix *Index) add(p *pin.Post) {
+	positions := make(map[string][]int)
+	pos, length := 0, 0
+	for _, field := range fields(p) {
+		for _, term := range field {
+			positions[term] = append(positions[term], pos)
+			pos++
+		}
+		length += len(field)
+		pos += fieldGap
+	}
+
+	d := &document{post: p, length: length}
+	for term, ps := range positions {
+		m := ix.postings[term]
+		if m == nil {
+			m = make(map[string][]int)
+			ix.postings[term] = m
+		}
+		m[p.URL] = ps
+		d.terms = append(d.terms, term)
+	}
+	ix.docs[p.URL] = d
+	ix.totalLen += d.length
+}
+
+func (ix *Index) remove(u string) {
+	d, ok := ix.docs[u]
+	if !ok {
+		return
+	}
+	for _, term := range d.terms {
+		m := ix.postings[term]
+		delete(m, u)
+		if len(m) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, u)
+	ix.totalLen -= d.length
+}
+
+// fields returns the tokens of each indexed field of p.
+func fields(p *pin.Post) [][]string {
+	var tags []string
+	for _, t := range p.Tags {
+		tags = append(tags, Tokenize(t)...)
+	}
+	return [][]string{
+		Tokenize(p.Title),
+		Tokenize(p.Description),
+		Tokenize(p.URL),
+		tags,
+	}
+}
+
+// Tokenize splits s into lower case terms at every character that is not a
+// letter or digit.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Query describes a search. The zero Query matches every post.
+type Query struct {
+	// Terms must all occur in a post and are used to rank the results.
+	Terms []string
+
+	// Phrases must each occur in a post as consecutive terms.
+	Phrases []string
+
+	// Tags must all be set on a post.
+	Tags []string
+
+	// From and To, if not zero, bound the time a post was saved.
+	From time.Time
+	To   time.Time
+
+	// ToRead, if not nil, selects posts by their to-read flag.
+	ToRead *bool
+}
+
+// ParseText builds a Query from free text, treating double-quoted parts as
+// phrases and everything else as terms.
+func ParseText(text string) Query {
+	var q Query
+	parts := strings.Split(text, `"`)
+	for i, part := range parts {
+		if i%2 == 1 {
+			if len(Tokenize(part)) > 0 {
+				q.Phrases = append(q.Phrases, part)
+			}
+			continue
+		}
+		q.Terms = append(q.Terms, Tokenize(part)...)
+	}
+	return q
+}
+
+// Result is a post matching a query with its relevance score.
+type Result struct {
+	Post  *pin.Post
+	Score float64
+}
+
+// Search returns the posts matching q, best first. Without terms, results are
+// ordered newest first. If limit is positive, at most limit results are
+// returned.
+func (ix *Index) Search(q Query, limit int) []Result {
+	var terms []string
+	for _, t := range q.Terms {
+		terms = append(terms, Tokenize(t)...)
+	}
+	var phrases [][]string
+	for _, ph := range q.Phrases {
+		if toks := Tokenize(ph); len(toks) > 0 {
+			phrases = append(phrases, toks)
+			terms = append(terms, toks...)
+		}
+	}
+
+	return ix.rank(terms, func(u string, d *document) bool {
+		if !ix.filter(d.post, q) {
+			return false
+		}
+		for _, term := range terms {
+			if len(ix.postings[term][u]) == 0 {
+				return false
+			}
+		}
+		for _, ph := range phrases {
+			if !ix.hasPhrase(u, ph) {
+				return false
+			}
+		}
+		return true
+	}, limit)
+}
+
+// rank scores every document accepted by match against terms and returns the
+// best limit of them. match is called with the read lock held.
+func (ix *Index) rank(terms []string, match func(u string, d *document) bool,
+	limit int) []Result {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var results []Result
+	for u, d := range ix.docs {
+		if match(u, d) {
+			results = append(results, Result{Post: d.post, Score: ix.score(u, d, terms)})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		ti, tj := results[i].Post.Time, results[j].Post.Time
+		if ti != nil && tj != nil && !ti.Equal(*tj) {
+			return ti.After(*tj)
+		}
+		return results[i].Post.URL < results[j].Post.URL
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (ix *Index) filter(p *pin.Post, q Query) bool {
+	for _, want := range q.Tags {
+		found := false
+		for _, t := range p.Tags {
+			if strings.EqualFold(t, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.ToRead != nil && p.ToRead != *q.ToRead {
+		return false
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		if p.Time == nil {
+			return false
+		}
+		if !q.From.IsZero() && p.Time.Before(q.From) {
+			return false
+		}
+		if !q.To.IsZero() && p.Time.After(q.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// score computes the BM25 score of the document at u for terms. Terms that do
+// not occur in the document add nothing.
+func (ix *Index) score(u string, d *document, terms []string) float64 {
+	n := float64(len(ix.docs))
+	avgLen := float64(ix.totalLen) / n
+	var score float64
+	for _, term := range terms {
+		m := ix.postings[term]
+		tf := float64(len(m[u]))
+		if tf == 0 {
+			continue
+		}
+		df := float64(len(m))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(d.length)/avgLen))
+	}
+	return score
+}
+
+// hasPhrase reports whether the terms of phrase occur consecutively in the
+// document at u.
+func (ix *Index) hasPhrase(u string, phrase []string) bool {
+	for _, start := range ix.postings[phrase[0]][u] {
+		found := true
+		for i, term := range phrase[1:] {
+			if !containsInt(ix.postings[term][u], start+i+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(s []int, v int) bool {
+	i := sort.SearchInts(s, v)
+	return i < len(s) && s[i] == v
+}
+
+// Save writes the indexed posts to w as JSON. The index itself is rebuilt by
+// Load, which keeps the file format independent of the index layout.
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	posts := make([]*pin.Post, 0, len(ix.docs))
+	for _, d := range ix.docs {
+		posts = append(posts, d.post)
+	}
+	ix.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool { return posts[i].URL < posts[j].URL })
+	return json.NewEncoder(w).Encode(posts)
+}
+
+// Load reads posts written by Save and returns an index over them.
+func Load(r io.Reader) (*Index, error) {
+	var posts []*pin.Post
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, err
+	}
+	ix := New()
+	ix.Add(posts...)
+	return ix, nil
+}
+
+// SaveFile saves the index to the file at path. The file is replaced
+// atomically, so a failed save leaves the previous index intact.
+func (ix *Index) SaveFile(path string) error {
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// LoadFile loads an index saved with SaveFile.
+func LoadFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,221 @@
+package search
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/watch"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+var posts = []*pin.Post{
+	{
+		URL:         "https://blog.golang.org/error-handling-and-go",
+		Title:       "Error handling and Go",
+		Description: "How to handle errors idiomatically.",
+		Tags:        []string{"golang", "errors"},
+		Time:        date(2021, time.March, 1),
+	},
+	{
+		URL:         "https://github.com/pkg/errors",
+		Title:       "pkg/errors: Simple error handling primitives",
+		Description: "Wrapping errors with handling context and stack traces.",
+		Tags:        []string{"golang", "library"},
+		Time:        date(2019, time.June, 1),
+		ToRead:      true,
+	},
+	{
+		URL:         "https://doc.rust-lang.org/book/ch09-00-error-handling.html",
+		Title:       "Error Handling - The Rust Programming Language",
+		Description: "Recoverable and unrecoverable errors.",
+		Tags:        []string{"rust"},
+		Time:        date(2022, time.January, 1),
+	},
+}
+
+func urls(results []Result) []string {
+	var us []string
+	for _, r := range results {
+		us = append(us, r.Post.URL)
+	}
+	return us
+}
+
+func TestSearchTerms(t *testing.T) {
+	ix := New()
+	ix.Add(posts...)
+
+	results := ix.Search(ParseText("golang errors"), 0)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results got %v", urls(results))
+	}
+	for _, r := range results {
+		if r.Score <= 0 {
+			t.Errorf("Expected positive score for %s got %f", r.Post.URL, r.Score)
+		}
+	}
+
+	if results := ix.Search(ParseText("rust"), 0); len(results) != 1 || results[0].Post != posts[2] {
+		t.Errorf("Wrong results for rust: %v", urls(results))
+	}
+	if results := ix.Search(ParseText("python"), 0); len(results) != 0 {
+		t.Errorf("Expected no results got %v", urls(results))
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	ix := New()
+	ix.Add(posts...)
+
+	// Only one post mentions "idiomatically".
+	results := ix.Search(ParseText("errors idiomatically"), 0)
+	if len(results) != 1 || results[0].Post != posts[0] {
+		t.Errorf("Wrong results %v", urls(results))
+	}
+
+	results = ix.Search(ParseText("golang"), 0)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results got %v", urls(results))
+	}
+	// The Go blog post has the term in both its URL and its tags.
+	if results[0].Post != posts[0] {
+		t.Errorf("Wrong ranking %v", urls(results))
+	}
+}
+
+func TestSearchPhrase(t *testing.T) {
+	ix := New()
+	ix.Add(posts...)
+
+	results := ix.Search(ParseText(`"error handling primitives"`), 0)
+	if len(results) != 1 || results[0].Post != posts[1] {
+		t.Errorf("Wrong phrase results %v", urls(results))
+	}
+
+	if results := ix.Search(ParseText(`"handling and go"`), 0); len(results) != 1 {
+		t.Errorf("Wrong phrase results %v", urls(results))
+	}
+	// The words occur in the post, but not next to each other.
+	if results := ix.Search(ParseText(`"handling go"`), 0); len(results) != 0 {
+		t.Errorf("Phrase matched separate words %v", urls(results))
+	}
+	if results := ix.Search(ParseText(`"go and handling"`), 0); len(results) != 0 {
+		t.Errorf("Phrase matched out of order %v", urls(results))
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	ix := New()
+	ix.Add(posts...)
+
+	toRead := true
+	tests := []struct {
+		q    Query
+		want int
+	}{
+		{Query{Tags: []string{"golang"}}, 2},
+		{Query{Tags: []string{"golang", "library"}}, 1},
+		{Query{ToRead: &toRead}, 1},
+		{Query{From: *date(2020, time.January, 1)}, 2},
+		{Query{From: *date(2020, time.January, 1), To: *date(2021, time.December, 31)}, 1},
+		{Query{Terms: []string{"handling"}, Tags: []string{"rust"}}, 1},
+		{Query{}, 3},
+	}
+	for _, tt := range tests {
+		if results := ix.Search(tt.q, 0); len(results) != tt.want {
+			t.Errorf("Query %+v: expected %d results got %v", tt.q, tt.want, urls(results))
+		}
+	}
+
+	results := ix.Search(Query{}, 1)
+	if len(results) != 1 || results[0].Post != posts[2] {
+		t.Errorf("Expected newest post first got %v", urls(results))
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	ix := New()
+	ix.Sync(posts)
+
+	edited := *posts[2]
+	edited.Title = "Fearless concurrency"
+	edited.Description = ""
+	ix.Apply(watch.Event{Type: watch.Updated, Post: &edited})
+	if results := ix.Search(ParseText("recoverable"), 0); len(results) != 0 {
+		t.Errorf("Stale terms still indexed: %v", urls(results))
+	}
+	if results := ix.Search(ParseText("fearless"), 0); len(results) != 1 {
+		t.Errorf("Updated post not found: %v", urls(results))
+	}
+
+	ix.Apply(watch.Event{Type: watch.Deleted, Post: &pin.Post{URL: posts[0].URL}})
+	if ix.Len() != 2 {
+		t.Errorf("Expected 2 posts after delete got %d", ix.Len())
+	}
+
+	ix.Sync(posts[:1])
+	if ix.Len() != 1 {
+		t.Errorf("Expected 1 post after sync got %d", ix.Len())
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	ix := New()
+	ix.Add(posts...)
+
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Len() != 3 {
+		t.Errorf("Expected 3 posts got %d", loaded.Len())
+	}
+	results := loaded.Search(ParseText(`"error handling primitives"`), 0)
+	if len(results) != 1 || results[0].Post.URL != posts[1].URL {
+		t.Errorf("Wrong results after load %v", urls(results))
+	}
+}
+
+func TestIndexSaveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.json")
+
+	ix := New()
+	ix.Add(posts...)
+	if err := ix.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	ix.Add(&pin.Post{URL: "https://example.com/"})
+	if err := ix.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 4 {
+		t.Errorf("Expected 4 posts got %d", loaded.Len())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected only the index file got %d files", len(files))
+	}
+}
@@ -0,0 +1,519 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// The query language describes saved searches such as
+//
+//	tag:golang -tag:read toread:yes site:github.com after:2023-01-01 "error handling"
+//
+// A query is a sequence of clauses that must all match. A clause is a word, a
+// double-quoted phrase, a field:value filter, a clause prefixed with - or NOT
+// to negate it, or a parenthesised query. Clauses joined by OR match if
+// either does. Words and phrases match the title, description, URL and tags
+// of a post. The fields are:
+//
+//	tag:T       the post has tag T
+//	site:H      the URL's host is H or a subdomain of it (host: is an alias)
+//	url:S       the URL contains S
+//	title:S     the title contains S
+//	after:D     saved on or after the date D, given as YYYY-MM-DD
+//	before:D    saved before the date D
+//	on:D        saved on the date D
+//	toread:B    the to-read flag is B, which is yes or no
+//	shared:B    the shared flag is B
+//
+// Field names and words are case-insensitive.
+
+// Expr is a node of a parsed query.
+type Expr interface {
+	// Match reports whether p satisfies the expression.
+	Match(p *pin.Post) bool
+	String() string
+}
+
+// AndExpr matches if all its clauses match.
+type AndExpr []Expr
+
+// OrExpr matches if any of its clauses match.
+type OrExpr []Expr
+
+// NotExpr matches if X does not.
+type NotExpr struct {
+	X Expr
+}
+
+// TermExpr matches posts containing the word.
+type TermExpr struct {
+	Term string
+}
+
+// PhraseExpr matches posts containing the words of the phrase consecutively
+// in one field.
+type PhraseExpr struct {
+	Phrase string
+}
+
+// FieldExpr matches posts by one of their fields.
+type FieldExpr struct {
+	Field string
+	Value string
+
+	date time.Time // for the date fields
+	flag bool      // for the flag fields
+}
+
+func (e AndExpr) Match(p *pin.Post) bool {
+	for _, x := range e {
+		if !x.Match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e AndExpr) String() string {
+	return joinExprs(e, " ")
+}
+
+func (e OrExpr) Match(p *pin.Post) bool {
+	for _, x := range e {
+		if x.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e OrExpr) String() string {
+	return "(" + joinExprs(e, " OR ") + ")"
+}
+
+func (e *NotExpr) Match(p *pin.Post) bool {
+	return !e.X.Match(p)
+}
+
+func (e *NotExpr) String() string {
+	return "-" + e.X.String()
+}
+
+func (e *TermExpr) Match(p *pin.Post) bool {
+	for _, field := range fields(p) {
+		for _, term := range field {
+			if term == e.Term {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *TermExpr) String() string {
+	return e.Term
+}
+
+func (e *PhraseExpr) Match(p *pin.Post) bool {
+	phrase := Tokenize(e.Phrase)
+	if len(phrase) == 0 {
+		return true
+	}
+	for _, field := range fields(p) {
+		for i := 0; i+len(phrase) <= len(field); i++ {
+			if equalStrings(field[i:i+len(phrase)], phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *PhraseExpr) String() string {
+	return fmt.Sprintf("%q", e.Phrase)
+}
+
+func (e *FieldExpr) Match(p *pin.Post) bool {
+	switch e.Field {
+	case "tag":
+		for _, t := range p.Tags {
+			if strings.EqualFold(t, e.Value) {
+				return true
+			}
+		}
+		return false
+	case "site":
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		return host == e.Value || strings.HasSuffix(host, "."+e.Value)
+	case "url":
+		return strings.Contains(strings.ToLower(p.URL), e.Value)
+	case "title":
+		return strings.Contains(strings.ToLower(p.Title), e.Value)
+	case "after":
+		return p.Time != nil && !p.Time.Before(e.date)
+	case "before":
+		return p.Time != nil && p.Time.Before(e.date)
+	case "on":
+		return p.Time != nil && !p.Time.Before(e.date) && p.Time.Before(e.date.AddDate(0, 0, 1))
+	case "toread":
+		return p.ToRead == e.flag
+	case "shared":
+		return p.Shared == e.flag
+	}
+	return false
+}
+
+func (e *FieldExpr) String() string {
+	if strings.ContainsAny(e.Value, " \t()\"") {
+		return fmt.Sprintf("%s:%q", e.Field, e.Value)
+	}
+	return e.Field + ":" + e.Value
+}
+
+func joinExprs(exprs []Expr, sep string) string {
+	s := make([]string, len(exprs))
+	for i, x := range exprs {
+		s[i] = x.String()
+	}
+	return strings.Join(s, sep)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the posts matching expr, in their original order.
+func Select(posts []*pin.Post, expr Expr) []*pin.Post {
+	var matched []*pin.Post
+	for _, p := range posts {
+		if expr.Match(p) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// SyntaxError reports an invalid query.
+type SyntaxError struct {
+	Pos int // byte offset of the problem in the query
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("search: syntax error at column %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField // a field name followed by a colon
+	tokMinus
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits a query into tokens.
+func lex(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '-':
+			toks = append(toks, token{tokMinus, "-", i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: i, Msg: "unterminated phrase"}
+			}
+			toks = append(toks, token{tokPhrase, s[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r()\"", rune(s[i])) {
+				if s[i] == ':' && i > start && isFieldName(s[start:i]) {
+					break
+				}
+				i++
+			}
+			if i < len(s) && s[i] == ':' && i > start {
+				toks = append(toks, token{tokField, strings.ToLower(s[start:i]), start})
+				i++
+				continue
+			}
+			word := s[start:i]
+			if c := strings.IndexByte(word, ':'); c > 0 && isLetters(word[:c]) &&
+				!strings.HasPrefix(word[c+1:], "//") {
+				return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unknown field %q", word[:c])}
+			}
+			toks = append(toks, token{tokWord, word, start})
+		}
+	}
+	return append(toks, token{tokEOF, "", len(s)}), nil
+}
+
+// fieldAliases maps every accepted field name to its canonical name.
+var fieldAliases = map[string]string{
+	"tag":    "tag",
+	"site":   "site",
+	"host":   "site",
+	"url":    "url",
+	"title":  "title",
+	"after":  "after",
+	"before": "before",
+	"on":     "on",
+	"toread": "toread",
+	"shared": "shared",
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isFieldName(s string) bool {
+	_, ok := fieldAliases[strings.ToLower(s)]
+	return ok
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// Parse parses a query. An empty query matches every post.
+func Parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return AndExpr{}, nil
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func isKeyword(t token, kw string) bool {
+	return t.kind == tokWord && t.text == kw
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := OrExpr{first}
+	for isKeyword(p.peek(), "OR") {
+		p.next()
+		x, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, x)
+	}
+	if len(or) == 1 {
+		return first, nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	var and AndExpr
+	for {
+		t := p.peek()
+		if t.kind == tokEOF || t.kind == tokRParen || isKeyword(t, "OR") {
+			break
+		}
+		if isKeyword(t, "AND") {
+			p.next()
+			continue
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, x)
+	}
+	switch len(and) {
+	case 0:
+		t := p.peek()
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a search term"}
+	case 1:
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if t := p.peek(); t.kind == tokMinus || isKeyword(t, "NOT") {
+		p.next()
+		if next := p.peek(); next.kind == tokEOF || next.kind == tokRParen {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "nothing to negate"}
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &SyntaxError{Pos: t.pos, Msg: "unclosed parenthesis"}
+		}
+		p.next()
+		return x, nil
+	case tokPhrase:
+		return &PhraseExpr{Phrase: t.text}, nil
+	case tokWord:
+		terms := Tokenize(t.text)
+		switch len(terms) {
+		case 0:
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("%q has no searchable characters", t.text)}
+		case 1:
+			return &TermExpr{Term: terms[0]}, nil
+		}
+		// Words such as "pkg/errors" hold several terms; require them all
+		// next to each other.
+		return &PhraseExpr{Phrase: t.text}, nil
+	case tokField:
+		return p.parseField(t)
+	case tokRParen:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected \")\""}
+	}
+	return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected end of query"}
+}
+
+func (p *parser) parseField(field token) (Expr, error) {
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokPhrase {
+		return nil, &SyntaxError{Pos: field.pos, Msg: fmt.Sprintf("missing value for %s:", field.text)}
+	}
+
+	name := fieldAliases[field.text]
+	e := &FieldExpr{Field: name, Value: v.text}
+	switch name {
+	case "tag":
+	case "site", "url", "title":
+		e.Value = strings.ToLower(v.text)
+	case "after", "before", "on":
+		d, err := time.Parse("2006-01-02", v.text)
+		if err != nil {
+			return nil, &SyntaxError{Pos: v.pos, Msg: fmt.Sprintf("invalid date %q, want YYYY-MM-DD", v.text)}
+		}
+		e.date = d
+	case "toread", "shared":
+		switch strings.ToLower(v.text) {
+		case "yes", "true":
+			e.flag = true
+		case "no", "false":
+		default:
+			return nil, &SyntaxError{Pos: v.pos, Msg: fmt.Sprintf("invalid %s value %q, want yes or no", name, v.text)}
+		}
+	}
+	return e, nil
+}
+
+// rankTerms returns the words and phrases of expr that a matching post must
+// contain, which are the ones used to score results. Terms under a negation
+// or an OR are left out.
+func rankTerms(expr Expr) (terms []string, phrases []string) {
+	switch e := expr.(type) {
+	case AndExpr:
+		for _, x := range e {
+			t, ph := rankTerms(x)
+			terms = append(terms, t...)
+			phrases = append(phrases, ph...)
+		}
+	case *TermExpr:
+		terms = append(terms, e.Term)
+	case *PhraseExpr:
+		phrases = append(phrases, e.Phrase)
+	}
+	return terms, phrases
+}
+
+// Query runs a query written in the query language against the index and
+// returns the matching posts, best first. If limit is positive, at most limit
+// results are returned.
+func (ix *Index) Query(q string, limit int) ([]Result, error) {
+	expr, err := Parse(q)
+	if err != nil {
+		return nil, err
+	}
+
+	terms, phrases := rankTerms(expr)
+	for _, ph := range phrases {
+		terms = append(terms, Tokenize(ph)...)
+	}
+	return ix.rank(terms, func(_ string, d *document) bool {
+		return expr.Match(d.post)
+	}, limit), nil
+}
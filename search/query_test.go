@@ -0,0 +1,133 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+var queryPosts = []*pin.Post{
+	{
+		URL:    "https://github.com/pkg/errors",
+		Title:  "pkg/errors: Simple error handling primitives",
+		Tags:   []string{"golang", "library"},
+		Time:   date(2023, time.February, 1),
+		ToRead: true,
+	},
+	{
+		URL:    "https://gist.github.com/someone/1234",
+		Title:  "Error handling snippets",
+		Tags:   []string{"golang", "read"},
+		Time:   date(2023, time.March, 1),
+		ToRead: true,
+		Shared: true,
+	},
+	{
+		URL:   "https://blog.golang.org/error-handling-and-go",
+		Title: "Error handling and Go",
+		Tags:  []string{"golang"},
+		Time:  date(2011, time.July, 12),
+	},
+	{
+		URL:    "https://notgithub.com/",
+		Title:  "Not GitHub",
+		Time:   date(2023, time.April, 1),
+		Shared: true,
+	},
+}
+
+var queryTests = []struct {
+	q    string
+	want []int // indexes into queryPosts
+}{
+	{`tag:golang -tag:read toread:yes site:github.com after:2023-01-01 "error handling"`, []int{0}},
+	{`tag:golang`, []int{0, 1, 2}},
+	{`TAG:GoLang -tag:read`, []int{0, 2}},
+	{`site:github.com`, []int{0, 1}},
+	{`host:gist.github.com`, []int{1}},
+	{`before:2023-01-01`, []int{2}},
+	{`on:2023-03-01`, []int{1}},
+	{`shared:yes`, []int{1, 3}},
+	{`shared:no toread:no`, []int{2}},
+	{`snippets OR primitives`, []int{0, 1}},
+	{`(snippets OR primitives) -toread:no shared:yes`, []int{1}},
+	{`NOT tag:golang`, []int{3}},
+	{`"handling and go"`, []int{2}},
+	{`pkg/errors`, []int{0}},
+	{`url:https://github.com/`, []int{0}},
+	{`title:"not github"`, []int{3}},
+	{``, []int{0, 1, 2, 3}},
+}
+
+func TestParseAndMatch(t *testing.T) {
+	for _, tt := range queryTests {
+		expr, err := Parse(tt.q)
+		if err != nil {
+			t.Errorf("%s: %v", tt.q, err)
+			continue
+		}
+
+		got := Select(queryPosts, expr)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s (%s): expected %d posts got %d", tt.q, expr, len(tt.want), len(got))
+			continue
+		}
+		for i, p := range got {
+			if p != queryPosts[tt.want[i]] {
+				t.Errorf("%s: result %d is %s", tt.q, i, p.URL)
+			}
+		}
+	}
+}
+
+var syntaxErrorTests = []struct {
+	q   string
+	pos int
+}{
+	{`"error handling`, 0},
+	{`tag:golang after:yesterday`, 17},
+	{`toread:maybe`, 7},
+	{`tga:golang`, 0},
+	{`(tag:golang`, 0},
+	{`tag:golang )`, 11},
+	{`golang -`, 7},
+	{`tag:`, 0},
+	{`golang OR`, 9},
+}
+
+func TestParseSyntaxErrors(t *testing.T) {
+	for _, tt := range syntaxErrorTests {
+		_, err := Parse(tt.q)
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%s: expected *SyntaxError got %T (%v)", tt.q, err, err)
+			continue
+		}
+		if serr.Pos != tt.pos {
+			t.Errorf("%s: expected error at %d got %d (%v)", tt.q, tt.pos, serr.Pos, serr)
+		}
+	}
+}
+
+func TestIndexQuery(t *testing.T) {
+	ix := New()
+	ix.Add(queryPosts...)
+
+	results, err := ix.Query(`tag:golang "error handling" -tag:read`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results got %v", urls(results))
+	}
+	for _, r := range results {
+		if r.Score <= 0 {
+			t.Errorf("Expected a positive score for %s", r.Post.URL)
+		}
+	}
+
+	if _, err := ix.Query(`tag:`, 0); err == nil {
+		t.Error("Expected syntax error")
+	}
+}
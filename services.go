@@ -0,0 +1,54 @@
+package pin
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PostsAPI is the method set of PostsService. Code that only needs to call the
+// API can depend on it and be tested against FakePostsService.
+type PostsAPI interface {
+	Add(urlStr, title, description string, tags []string,
+		creationTime *time.Time, replace, shared, toread bool) (*http.Response, error)
+	Delete(urlStr string) (*http.Response, error)
+	Get(tags []string, creationTime *time.Time, urlStr string) ([]*Post, *http.Response, error)
+	LastTimeUpdated() (*time.Time, *http.Response, error)
+	Dates(tags []string) ([]*Date, *http.Response, error)
+	Recent(tags []string, count int) ([]*Post, *http.Response, error)
+	All(tags []string, start int, results int, fromdt, todt *time.Time) ([]*Post, *http.Response, error)
+	Suggest(urlStr string) ([]string, []string, *http.Response, error)
+}
+
+// TagsAPI is the method set of TagsService.
+type TagsAPI interface {
+	Get() ([]*Tag, *http.Response, error)
+	Delete(tag string) (*http.Response, error)
+	Rename(newTag, oldTag string) (*http.Response, error)
+}
+
+// UserAPI is the method set of UserService.
+type UserAPI interface {
+	SecretRSSKey() (string, *http.Response, error)
+	APIToken() (string, *http.Response, error)
+}
+
+// NotesAPI is the method set of NotesService.
+type NotesAPI interface {
+	List() ([]*Note, *http.Response, error)
+	Get(id string) (*Note, *http.Response, error)
+}
+
+// FeedsAPI is the method set of FeedsService.
+type FeedsAPI interface {
+	URL(feed Feed, format FeedFormat, count int) (*url.URL, error)
+	Fetch(feed Feed, format FeedFormat, count int) ([]*Post, *http.Response, error)
+}
+
+var (
+	_ PostsAPI = (*PostsService)(nil)
+	_ TagsAPI  = (*TagsService)(nil)
+	_ UserAPI  = (*UserService)(nil)
+	_ NotesAPI = (*NotesService)(nil)
+	_ FeedsAPI = (*FeedsService)(nil)
+)
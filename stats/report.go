@@ -0,0 +1,177 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// dateLayout formats bucket and streak dates in tables.
+const dateLayout = "2006-01-02"
+
+// Report gathers the statistics of an account.
+type Report struct {
+	Daily     []Bucket    `json:"daily"`
+	Weekly    []Bucket    `json:"weekly"`
+	Monthly   []Bucket    `json:"monthly"`
+	Longest   Streak      `json:"longest_streak"`
+	Current   Streak      `json:"current_streak"`
+	TagGrowth []Series    `json:"tag_growth,omitempty"`
+	TopHosts  []HostCount `json:"top_hosts"`
+	ToRead    []AgeBucket `json:"to_read"`
+	Sharing   Sharing     `json:"sharing"`
+}
+
+// Options selects what goes into a report.
+type Options struct {
+	Tags     []string // tags to chart the growth of
+	Period   Period   // the bucket width of the tag growth histograms
+	TopHosts int      // the number of hosts listed; zero lists 10
+	Now      time.Time
+}
+
+// Build compiles a report from the per-day counts of posts.Dates and from
+// all, the posts of the account as returned by PostsService.All.
+func Build(posts pin.PostsAPI, all []*pin.Post, opts Options) (*Report, error) {
+	dates, _, err := posts.Dates(nil)
+	if err != nil {
+		return nil, fmt.Errorf("stats: dates: %v", err)
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	top := opts.TopHosts
+	if top <= 0 {
+		top = 10
+	}
+
+	r := &Report{
+		Daily:    Histogram(dates, Day),
+		Weekly:   Histogram(dates, Week),
+		Monthly:  Histogram(dates, Month),
+		TopHosts: TopHosts(all, top),
+		ToRead:   ToReadAgeing(all, now),
+		Sharing:  CountSharing(all),
+	}
+	r.Longest, r.Current = Streaks(dates, now)
+	if len(opts.Tags) > 0 {
+		if r.TagGrowth, err = TagGrowth(posts, opts.Tags, opts.Period); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Table is a titled grid of values, ready to be written as text or CSV.
+type Table struct {
+	Title   string
+	Columns []string
+	Rows    [][]string
+}
+
+// HistogramTable lays out buckets as a table.
+func HistogramTable(title string, buckets []Bucket) *Table {
+	t := &Table{Title: title, Columns: []string{"start", "posts", "total"}}
+	for _, b := range buckets {
+		t.Rows = append(t.Rows, []string{b.Start.Format(dateLayout),
+			strconv.Itoa(b.Count), strconv.Itoa(b.Total)})
+	}
+	return t
+}
+
+// Tables lays out the report as tables, one per statistic.
+func (r *Report) Tables() []*Table {
+	tables := []*Table{
+		HistogramTable("Posts per day", r.Daily),
+		HistogramTable("Posts per week", r.Weekly),
+		HistogramTable("Posts per month", r.Monthly),
+	}
+
+	streaks := &Table{Title: "Streaks", Columns: []string{"streak", "start", "end", "days"}}
+	for _, s := range []struct {
+		name string
+		s    Streak
+	}{{"longest", r.Longest}, {"current", r.Current}} {
+		if s.s.Days == 0 {
+			streaks.Rows = append(streaks.Rows, []string{s.name, "", "", "0"})
+			continue
+		}
+		streaks.Rows = append(streaks.Rows, []string{s.name, s.s.Start.Format(dateLayout),
+			s.s.End.Format(dateLayout), strconv.Itoa(s.s.Days)})
+	}
+	tables = append(tables, streaks)
+
+	if len(r.TagGrowth) > 0 {
+		growth := &Table{Title: "Tag growth", Columns: []string{"tag", "start", "posts", "total"}}
+		for _, s := range r.TagGrowth {
+			for _, b := range s.Buckets {
+				growth.Rows = append(growth.Rows, []string{s.Tag, b.Start.Format(dateLayout),
+					strconv.Itoa(b.Count), strconv.Itoa(b.Total)})
+			}
+		}
+		tables = append(tables, growth)
+	}
+
+	hosts := &Table{Title: "Top hosts", Columns: []string{"host", "posts"}}
+	for _, h := range r.TopHosts {
+		hosts.Rows = append(hosts.Rows, []string{h.Host, strconv.Itoa(h.Count)})
+	}
+	toread := &Table{Title: "To-read backlog", Columns: []string{"age", "posts"}}
+	for _, b := range r.ToRead {
+		toread.Rows = append(toread.Rows, []string{b.Label, strconv.Itoa(b.Count)})
+	}
+	sharing := &Table{
+		Title:   "Sharing",
+		Columns: []string{"shared", "private", "shared ratio"},
+		Rows: [][]string{{strconv.Itoa(r.Sharing.Shared), strconv.Itoa(r.Sharing.Private),
+			strconv.FormatFloat(r.Sharing.SharedRatio(), 'f', 2, 64)}},
+	}
+	return append(tables, hosts, toread, sharing)
+}
+
+// WriteText writes tables to w as aligned columns under their titles,
+// separated by blank lines.
+func WriteText(w io.Writer, tables []*Table) error {
+	for i, t := range tables {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", t.Title); err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.Columns, "\t"))
+		for _, row := range t.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes t to w as CSV with a header row.
+func WriteCSV(w io.Writer, t *Table) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.Columns)
+	cw.WriteAll(t.Rows)
+	return cw.Error()
+}
+
+// WriteJSON writes r to w as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,248 @@
+// Package stats summarises the activity of an account: how many bookmarks
+// were saved when, streaks of daily bookmarking, how tags grew, which sites
+// are bookmarked most, how old the to-read backlog is and how much is shared.
+package stats
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// Period is the width of a histogram bucket.
+type Period int
+
+const (
+	Day  Period = iota
+	Week        // starting on Monday
+	Month
+)
+
+func (p Period) String() string {
+	switch p {
+	case Day:
+		return "day"
+	case Week:
+		return "week"
+	case Month:
+		return "month"
+	}
+	return fmt.Sprintf("Period(%d)", int(p))
+}
+
+// start returns the start of the period containing t, in UTC.
+func (p Period) start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch p {
+	case Week:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (p Period) next(t time.Time) time.Time {
+	switch p {
+	case Week:
+		return t.AddDate(0, 0, 7)
+	case Month:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// Bucket counts the posts saved in one period.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+	Total int       `json:"total"` // the posts saved up to the end of the period
+}
+
+// Histogram buckets the per-day counts returned by PostsService.Dates by
+// period. Periods without posts between the first and last are included, so
+// the buckets can be plotted directly.
+func Histogram(dates []*pin.Date, period Period) []Bucket {
+	counts := make(map[time.Time]int)
+	var first, last time.Time
+	for _, d := range dates {
+		if d.Date == nil || d.Count == 0 {
+			continue
+		}
+		s := period.start(*d.Date)
+		counts[s] += d.Count
+		if first.IsZero() || s.Before(first) {
+			first = s
+		}
+		if s.After(last) {
+			last = s
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	var buckets []Bucket
+	total := 0
+	for s := first; !s.After(last); s = period.next(s) {
+		total += counts[s]
+		buckets = append(buckets, Bucket{Start: s, Count: counts[s], Total: total})
+	}
+	return buckets
+}
+
+// Streak is a run of consecutive days with at least one post saved.
+type Streak struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Days  int       `json:"days"`
+}
+
+// Streaks returns the longest streak in dates, and the current one: the
+// streak that includes the day of now or the day before, if any. The
+// earliest of equally long streaks is the longest.
+func Streaks(dates []*pin.Date, now time.Time) (longest, current Streak) {
+	days := Histogram(dates, Day)
+
+	var run Streak
+	for _, b := range days {
+		if b.Count == 0 {
+			run = Streak{}
+			continue
+		}
+		if run.Days == 0 {
+			run.Start = b.Start
+		}
+		run.End = b.Start
+		run.Days++
+		if run.Days > longest.Days {
+			longest = run
+		}
+	}
+
+	today := Day.start(now)
+	if run.Days > 0 && !run.End.Before(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return longest, current
+}
+
+// Series is the histogram of the posts with one tag.
+type Series struct {
+	Tag     string   `json:"tag"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// TagGrowth returns a histogram for each of tags, built from
+// posts.Dates([]string{tag}).
+func TagGrowth(posts pin.PostsAPI, tags []string, period Period) ([]Series, error) {
+	series := make([]Series, len(tags))
+	for i, tag := range tags {
+		dates, _, err := posts.Dates([]string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("stats: dates for %s: %v", tag, err)
+		}
+		series[i] = Series{Tag: tag, Buckets: Histogram(dates, period)}
+	}
+	return series, nil
+}
+
+// HostCount is the number of posts from a host.
+type HostCount struct {
+	Host  string `json:"host"`
+	Count int    `json:"count"`
+}
+
+// TopHosts returns the n hosts with the most posts, most first. A leading
+// "www." is ignored. If n is zero or less, every host is returned.
+func TopHosts(posts []*pin.Post, n int) []HostCount {
+	counts := make(map[string]int)
+	for _, p := range posts {
+		u, err := url.Parse(p.URL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		counts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")]++
+	}
+
+	hosts := make([]HostCount, 0, len(counts))
+	for h, c := range counts {
+		hosts = append(hosts, HostCount{h, c})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Count != hosts[j].Count {
+			return hosts[i].Count > hosts[j].Count
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	if n > 0 && len(hosts) > n {
+		hosts = hosts[:n]
+	}
+	return hosts
+}
+
+// AgeBucket counts the unread posts within an age range.
+type AgeBucket struct {
+	Label string        `json:"label"`
+	Max   time.Duration `json:"-"` // exclusive; zero for the last bucket
+	Count int           `json:"count"`
+}
+
+// ToReadAgeing counts the posts marked to read by how long ago they were
+// saved. Posts without a time are left out.
+func ToReadAgeing(posts []*pin.Post, now time.Time) []AgeBucket {
+	const day = 24 * time.Hour
+	buckets := []AgeBucket{
+		{Label: "under a week", Max: 7 * day},
+		{Label: "1-4 weeks", Max: 28 * day},
+		{Label: "1-3 months", Max: 91 * day},
+		{Label: "3-12 months", Max: 365 * day},
+		{Label: "over a year"},
+	}
+	for _, p := range posts {
+		if !p.ToRead || p.Time == nil {
+			continue
+		}
+		age := now.Sub(*p.Time)
+		for i := range buckets {
+			if buckets[i].Max == 0 || age < buckets[i].Max {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+// Sharing counts shared and private posts.
+type Sharing struct {
+	Shared  int `json:"shared"`
+	Private int `json:"private"`
+}
+
+// SharedRatio returns the fraction of posts that are shared, or 0 if there
+// are none.
+func (s Sharing) SharedRatio() float64 {
+	if s.Shared+s.Private == 0 {
+		return 0
+	}
+	return float64(s.Shared) / float64(s.Shared+s.Private)
+}
+
+// CountSharing counts the shared and private posts among posts.
+func CountSharing(posts []*pin.Post) Sharing {
+	var s Sharing
+	for _, p := range posts {
+		if p.Shared {
+			s.Shared++
+		} else {
+			s.Private++
+		}
+	}
+	return s
+}
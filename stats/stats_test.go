@@ -0,0 +1,186 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(counts map[string]int) []*pin.Date {
+	var out []*pin.Date
+	for s, n := range counts {
+		d := day(s)
+		out = append(out, &pin.Date{Date: &d, Count: n})
+	}
+	return out
+}
+
+// 2020-01-06 is a Monday.
+var testDates = dates(map[string]int{
+	"2020-01-06": 2,
+	"2020-01-07": 1,
+	"2020-01-08": 3,
+	"2020-01-12": 1,
+	"2020-01-13": 4,
+	"2020-02-03": 1,
+})
+
+func counts(buckets []Bucket) []int {
+	var out []int
+	for _, b := range buckets {
+		out = append(out, b.Count)
+	}
+	return out
+}
+
+func TestHistogram(t *testing.T) {
+	daily := Histogram(testDates, Day)
+	if len(daily) != 29 || !daily[0].Start.Equal(day("2020-01-06")) || daily[28].Total != 12 {
+		t.Errorf("Wrong daily histogram %+v", daily)
+	}
+
+	weekly := Histogram(testDates, Week)
+	if got := counts(weekly); !reflect.DeepEqual(got, []int{7, 4, 0, 0, 1}) {
+		t.Errorf("Wrong weekly counts %v", got)
+	}
+	if !weekly[1].Start.Equal(day("2020-01-13")) {
+		t.Errorf("Week does not start on Monday: %s", weekly[1].Start)
+	}
+
+	monthly := Histogram(testDates, Month)
+	if got := counts(monthly); !reflect.DeepEqual(got, []int{11, 1}) {
+		t.Errorf("Wrong monthly counts %v", got)
+	}
+	if Histogram(nil, Day) != nil {
+		t.Error("Expected no buckets for no dates")
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	longest, current := Streaks(testDates, day("2020-02-04").Add(10*time.Hour))
+	if !longest.Start.Equal(day("2020-01-06")) || longest.Days != 3 {
+		t.Errorf("Wrong longest streak %+v", longest)
+	}
+	if !current.Start.Equal(day("2020-02-03")) || current.Days != 1 {
+		t.Errorf("Wrong current streak %+v", current)
+	}
+
+	if _, current := Streaks(testDates, day("2020-02-05")); current.Days != 0 {
+		t.Errorf("Expected no current streak got %+v", current)
+	}
+}
+
+func TestPosts(t *testing.T) {
+	now := day("2020-06-01")
+	ago := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	posts := []*pin.Post{
+		{URL: "https://www.github.com/a", ToRead: true, Time: ago(2), Shared: true},
+		{URL: "https://github.com/b", ToRead: true, Time: ago(40)},
+		{URL: "https://example.com/", ToRead: true, Time: ago(400)},
+		{URL: "https://golang.org/", Shared: true, Time: ago(1)},
+	}
+
+	hosts := TopHosts(posts, 2)
+	if !reflect.DeepEqual(hosts, []HostCount{{"github.com", 2}, {"example.com", 1}}) {
+		t.Errorf("Wrong top hosts %v", hosts)
+	}
+
+	var aged []int
+	for _, b := range ToReadAgeing(posts, now) {
+		aged = append(aged, b.Count)
+	}
+	if !reflect.DeepEqual(aged, []int{1, 0, 1, 0, 1}) {
+		t.Errorf("Wrong ageing %v", aged)
+	}
+
+	s := CountSharing(posts)
+	if s.Shared != 2 || s.Private != 2 || s.SharedRatio() != 0.5 {
+		t.Errorf("Wrong sharing %+v", s)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	fake := &pin.FakePostsService{
+		DatesStub: func(tags []string) ([]*pin.Date, *http.Response, error) {
+			if len(tags) == 0 {
+				return testDates, nil, nil
+			}
+			return dates(map[string]int{"2020-01-08": 1, "2020-02-10": 2}), nil, nil
+		},
+	}
+	posts := []*pin.Post{{URL: "https://golang.org/", Shared: true}}
+	r, err := Build(fake, posts, Options{Tags: []string{"go"}, Period: Month, Now: day("2020-02-03")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.DatesCalls(); len(calls) != 2 || !reflect.DeepEqual(calls[1], []string{"go"}) {
+		t.Errorf("Wrong calls to Dates %v", calls)
+	}
+	if len(r.TagGrowth) != 1 || !reflect.DeepEqual(counts(r.TagGrowth[0].Buckets), []int{1, 2}) {
+		t.Errorf("Wrong tag growth %+v", r.TagGrowth)
+	}
+	if r.Current.Days != 1 {
+		t.Errorf("Wrong current streak %+v", r.Current)
+	}
+
+	tables := r.Tables()
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Posts per month\nstart       posts  total\n2020-01-01  11     11\n2020-02-01  1      12\n",
+		"longest  2020-01-06  2020-01-08  3\n",
+		"go   2020-02-01  2      3\n",
+		"Sharing\nshared  private  shared ratio\n1       0        1.00\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Text output lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteCSV(&buf, tables[2]); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "start,posts,total\n2020-01-01,11,11\n2020-02-01,1,12\n" {
+		t.Errorf("Wrong CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["sharing"].(map[string]interface{})["shared"] != 1.0 || len(out["monthly"].([]interface{})) != 2 {
+		t.Errorf("Wrong JSON:\n%s", buf.String())
+	}
+
+	fake.DatesStub = func([]string) ([]*pin.Date, *http.Response, error) {
+		return nil, nil, errors.New("boom")
+	}
+	if _, err := Build(fake, posts, Options{}); err == nil {
+		t.Error("Expected error from Dates")
+	}
+}
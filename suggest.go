@@ -0,0 +1,202 @@
+package pin
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scores given to suggested tags by their source. A tag suggested by both
+// scores the sum.
+const (
+	popularScore     = 1
+	recommendedScore = 2
+)
+
+// Suggestion is a tag suggested for a URL. Tags are lower case.
+type Suggestion struct {
+	Tag         string
+	Popular     bool // used site-wide for the URL
+	Recommended bool // drawn from the user's own tags
+	Score       int
+}
+
+// Suggestions is a ranked list of suggested tags, best first.
+type Suggestions []Suggestion
+
+// Tags returns the suggested tags in order.
+func (s Suggestions) Tags() []string {
+	tags := make([]string, len(s))
+	for i, sg := range s {
+		tags[i] = sg.Tag
+	}
+	return tags
+}
+
+// MergeSuggestions combines the popular and recommended lists returned by
+// PostsService.Suggest. Tags are lower cased and duplicates merged.
+// Suggestions are ranked by score; ties keep the order the tags were first
+// seen in, recommended before popular.
+func MergeSuggestions(popular, recommended []string) Suggestions {
+	var out Suggestions
+	index := make(map[string]int)
+	add := func(tag string, rec bool) {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return
+		}
+		i, ok := index[tag]
+		if !ok {
+			i = len(out)
+			index[tag] = i
+			out = append(out, Suggestion{Tag: tag})
+		}
+		s := &out[i]
+		if rec && !s.Recommended {
+			s.Recommended = true
+			s.Score += recommendedScore
+		}
+		if !rec && !s.Popular {
+			s.Popular = true
+			s.Score += popularScore
+		}
+	}
+
+	for _, t := range recommended {
+		add(t, true)
+	}
+	for _, t := range popular {
+		add(t, false)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// Suggester returns merged tag suggestions, caching them per URL. It is safe
+// for concurrent use.
+type Suggester struct {
+	// Posts is used to fetch suggestions.
+	Posts PostsAPI
+
+	// Tags, if set, is used to leave out the tags the user already has, so
+	// that only tags new to the user are suggested.
+	Tags TagsAPI
+
+	// TTL is how long results, and the user's tags, are cached. Zero
+	// disables caching.
+	TTL time.Duration
+
+	now   func() time.Time // for tests
+	mu    sync.Mutex
+	cache map[string]cachedSuggestions
+	known cachedTags
+}
+
+type cachedSuggestions struct {
+	s       Suggestions
+	expires time.Time
+}
+
+type cachedTags struct {
+	tags    map[string]bool
+	expires time.Time
+}
+
+// NewSuggester returns a Suggester using the services of c. If filterKnown
+// is set, tags the user already has are left out.
+func NewSuggester(c *Client, ttl time.Duration, filterKnown bool) *Suggester {
+	s := &Suggester{Posts: c.Posts, TTL: ttl}
+	if filterKnown {
+		s.Tags = c.Tags
+	}
+	return s
+}
+
+func (s *Suggester) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Suggest returns the suggestions for urlStr. Errors are not cached.
+func (s *Suggester) Suggest(urlStr string) (Suggestions, error) {
+	now := s.clock()
+	s.mu.Lock()
+	c, ok := s.cache[urlStr]
+	s.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return append(Suggestions(nil), c.s...), nil
+	}
+
+	popular, recommended, _, err := s.Posts.Suggest(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	sugg := MergeSuggestions(popular, recommended)
+
+	if s.Tags != nil {
+		known, err := s.knownTags(now)
+		if err != nil {
+			return nil, err
+		}
+		filtered := sugg[:0]
+		for _, sg := range sugg {
+			if !known[sg.Tag] {
+				filtered = append(filtered, sg)
+			}
+		}
+		sugg = filtered
+	}
+
+	if s.TTL > 0 {
+		s.mu.Lock()
+		if s.cache == nil {
+			s.cache = make(map[string]cachedSuggestions)
+		}
+		// Drop expired entries so the cache does not grow without bound.
+		for u, c := range s.cache {
+			if !now.Before(c.expires) {
+				delete(s.cache, u)
+			}
+		}
+		s.cache[urlStr] = cachedSuggestions{append(Suggestions(nil), sugg...), now.Add(s.TTL)}
+		s.mu.Unlock()
+	}
+	return sugg, nil
+}
+
+// knownTags returns the lower cased tags of the user.
+func (s *Suggester) knownTags(now time.Time) (map[string]bool, error) {
+	s.mu.Lock()
+	known := s.known
+	s.mu.Unlock()
+	if known.tags != nil && now.Before(known.expires) {
+		return known.tags, nil
+	}
+
+	tags, _, err := s.Tags.Get()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		set[strings.ToLower(t.Name)] = true
+	}
+
+	if s.TTL > 0 {
+		s.mu.Lock()
+		s.known = cachedTags{set, now.Add(s.TTL)}
+		s.mu.Unlock()
+	}
+	return set, nil
+}
+
+// Clear empties the cache.
+func (s *Suggester) Clear() {
+	s.mu.Lock()
+	s.cache = nil
+	s.known = cachedTags{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,84 @@
+package pin
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestMergeSuggestions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.pinboard.in/v1/posts/suggest?auth_token=user%3Atoken&url=https%3A%2F%2Fexample.org",
+		httpmock.NewStringResponder(200, readFixture("posts_suggest")))
+
+	s, err := NewSuggester(client, 0, false).Suggest("https://example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"blog", "blogs", "writing", "people", "weblog", "travel",
+		"art", "humor", "programming", "culture"}
+	if !reflect.DeepEqual(s.Tags(), expected) {
+		t.Errorf("Wrong order %v", s.Tags())
+	}
+	if p := s[3]; !p.Popular || !p.Recommended || p.Score != popularScore+recommendedScore {
+		t.Errorf("Wrong merge of People and people %+v", p)
+	}
+	if w := s[4]; w.Popular || !w.Recommended || w.Score != recommendedScore {
+		t.Errorf("Wrong flags %+v", w)
+	}
+}
+
+func TestSuggesterCache(t *testing.T) {
+	posts := &FakePostsService{
+		SuggestStub: func(string) ([]string, []string, *http.Response, error) {
+			return []string{"Go", "news"}, []string{"go", "code"}, nil, nil
+		},
+	}
+	tags := &FakeTagsService{
+		GetStub: func() ([]*Tag, *http.Response, error) {
+			return []*Tag{{Name: "Code", Count: 3}}, nil, nil
+		},
+	}
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := &Suggester{Posts: posts, Tags: tags, TTL: time.Minute, now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		got, err := s.Suggest("https://example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Tags(), []string{"go", "news"}) {
+			t.Errorf("Wrong suggestions %v", got.Tags())
+		}
+		got[0].Tag = "changed"
+	}
+	if n := len(posts.SuggestCalls()); n != 1 {
+		t.Errorf("Expected 1 call to Suggest got %d", n)
+	}
+
+	s.Suggest("https://example.com")
+	if n := tags.GetCallCount(); n != 1 {
+		t.Errorf("Expected user's tags to be fetched once got %d", n)
+	}
+
+	now = now.Add(time.Minute)
+	s.Suggest("https://example.org")
+	if n := len(posts.SuggestCalls()); n != 3 {
+		t.Errorf("Expected expired entry to be refetched got %d calls", n)
+	}
+
+	posts.SuggestStub = func(string) ([]string, []string, *http.Response, error) {
+		return nil, nil, nil, errors.New("boom")
+	}
+	s.Clear()
+	if _, err := s.Suggest("https://example.org"); err == nil {
+		t.Error("Expected error after clearing the cache")
+	}
+}
@@ -0,0 +1,128 @@
+package taggraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes g to w in the Graphviz DOT language, with the post counts
+// of tags and the weights of edges as attributes.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b bytes.Buffer
+	b.WriteString("graph tags {\n")
+	for _, t := range g.Tags() {
+		fmt.Fprintf(&b, "\t%s [count=%d];\n", dotID(t), g.counts[t])
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "\t%s -- %s [weight=%d];\n", dotID(e.A), dotID(e.B), e.Weight)
+	}
+	b.WriteString("}\n")
+	_, err := b.WriteTo(w)
+	return err
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func dotID(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value int    `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string      `xml:"id,attr"`
+	Data graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Data   graphMLData `xml:"data"`
+}
+
+// WriteGraphML writes g to w as GraphML, for tools such as Gephi and yEd.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "count", For: "node", Name: "count", Type: "int"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+		},
+	}
+	doc.Graph.ID = "tags"
+	doc.Graph.EdgeDefault = "undirected"
+	for _, t := range g.Tags() {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{t, graphMLData{"count", g.counts[t]}})
+	}
+	for _, e := range g.Edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{e.A, e.B, graphMLData{"weight", e.Weight}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+type jsonEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// WriteJSON writes g to w as a JSON object with "nodes" and "edges" arrays,
+// the layout expected by d3-force and similar libraries.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	out := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, t := range g.Tags() {
+		out.Nodes = append(out.Nodes, jsonNode{t, g.counts[t]})
+	}
+	for _, e := range g.Edges() {
+		out.Edges = append(out.Edges, jsonEdge{e.A, e.B, e.Weight})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,228 @@
+// Package taggraph analyses how tags are used together. It builds a graph
+// with a node per tag and an edge between tags that share posts, weighted by
+// the number of posts they share.
+package taggraph
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/zachlatta/pin"
+)
+
+// Edge joins two tags used on the same posts. A sorts before B.
+type Edge struct {
+	A, B   string
+	Weight int // the number of posts with both tags
+}
+
+type pair struct{ a, b string }
+
+func newPair(a, b string) pair {
+	if b < a {
+		a, b = b, a
+	}
+	return pair{a, b}
+}
+
+// Graph is a tag co-occurrence graph. Tags are lower cased, since Pinboard
+// treats tags that differ only in case as the same tag.
+type Graph struct {
+	counts map[string]int
+	edges  map[pair]int
+	adj    map[string]map[string]int
+}
+
+// Build returns the graph of the tags of posts.
+func Build(posts []*pin.Post) *Graph {
+	g := &Graph{
+		counts: make(map[string]int),
+		edges:  make(map[pair]int),
+		adj:    make(map[string]map[string]int),
+	}
+	for _, p := range posts {
+		seen := make(map[string]bool, len(p.Tags))
+		var tags []string
+		for _, t := range p.Tags {
+			t = strings.ToLower(t)
+			if t != "" && !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+
+		for i, a := range tags {
+			g.counts[a]++
+			for _, b := range tags[i+1:] {
+				g.edges[newPair(a, b)]++
+				g.link(a, b)
+				g.link(b, a)
+			}
+		}
+	}
+	return g
+}
+
+func (g *Graph) link(a, b string) {
+	if g.adj[a] == nil {
+		g.adj[a] = make(map[string]int)
+	}
+	g.adj[a][b]++
+}
+
+// Tags returns the tags of the graph in order.
+func (g *Graph) Tags() []string {
+	tags := make([]string, 0, len(g.counts))
+	for t := range g.counts {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Count returns the number of posts with tag.
+func (g *Graph) Count(tag string) int {
+	return g.counts[strings.ToLower(tag)]
+}
+
+// Edges returns the edges of the graph, heaviest first.
+func (g *Graph) Edges() []Edge {
+	edges := make([]Edge, 0, len(g.edges))
+	for p, w := range g.edges {
+		edges = append(edges, Edge{p.a, p.b, w})
+	}
+	sortEdges(edges)
+	return edges
+}
+
+// Neighbors returns the edges of tag, heaviest first, with tag as A.
+func (g *Graph) Neighbors(tag string) []Edge {
+	tag = strings.ToLower(tag)
+	var edges []Edge
+	for t, w := range g.adj[tag] {
+		edges = append(edges, Edge{tag, t, w})
+	}
+	sortEdges(edges)
+	return edges
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		switch {
+		case edges[i].Weight != edges[j].Weight:
+			return edges[i].Weight > edges[j].Weight
+		case edges[i].A != edges[j].A:
+			return edges[i].A < edges[j].A
+		}
+		return edges[i].B < edges[j].B
+	})
+}
+
+// Clusters returns the groups of tags connected by edges of at least
+// minWeight, largest first. Each cluster is sorted, and tags without such
+// edges are left out.
+func (g *Graph) Clusters(minWeight int) [][]string {
+	seen := make(map[string]bool)
+	var clusters [][]string
+	for _, start := range g.Tags() {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+
+		cluster := []string{start}
+		for i := 0; i < len(cluster); i++ {
+			for t, w := range g.adj[cluster[i]] {
+				if w >= minWeight && !seen[t] {
+					seen[t] = true
+					cluster = append(cluster, t)
+				}
+			}
+		}
+		if len(cluster) > 1 {
+			sort.Strings(cluster)
+			clusters = append(clusters, cluster)
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return len(clusters[i]) > len(clusters[j]) })
+	return clusters
+}
+
+// Orphans returns the tags never used together with another tag.
+func (g *Graph) Orphans() []string {
+	var orphans []string
+	for _, t := range g.Tags() {
+		if len(g.adj[t]) == 0 {
+			orphans = append(orphans, t)
+		}
+	}
+	return orphans
+}
+
+// Synonym is a pair of tags that probably mean the same thing.
+type Synonym struct {
+	A, B       string
+	Reason     string  // "spelling" or "context"
+	Similarity float64 // 1 for spelling variants
+}
+
+// Synonyms returns pairs of tags that are likely to be synonyms: spelling
+// variants such as "web-dev" and "webdev" or "blog" and "blogs", and tags
+// that are never used together but are used with the same other tags, with a
+// cosine similarity of at least minSimilarity. Pairs are ordered by
+// similarity.
+func (g *Graph) Synonyms(minSimilarity float64) []Synonym {
+	tags := g.Tags()
+	var out []Synonym
+	for i, a := range tags {
+		for _, b := range tags[i+1:] {
+			if spellingKey(a) == spellingKey(b) {
+				out = append(out, Synonym{a, b, "spelling", 1})
+				continue
+			}
+			if g.edges[newPair(a, b)] > 0 {
+				continue
+			}
+			if sim := g.similarity(a, b); sim >= minSimilarity && sim > 0 {
+				out = append(out, Synonym{a, b, "context", sim})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	return out
+}
+
+// spellingKey strips the differences between spelling variants of a tag:
+// separators and a plural "s".
+func spellingKey(tag string) string {
+	key := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '.', '/', ':':
+			return -1
+		}
+		return r
+	}, tag)
+	if len(key) > 3 && strings.HasSuffix(key, "s") && !strings.HasSuffix(key, "ss") {
+		key = key[:len(key)-1]
+	}
+	return key
+}
+
+// similarity is the cosine similarity of the co-occurrence vectors of a and
+// b.
+func (g *Graph) similarity(a, b string) float64 {
+	va, vb := g.adj[a], g.adj[b]
+	if len(va) == 0 || len(vb) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for t, w := range va {
+		na += float64(w * w)
+		dot += float64(w * vb[t])
+	}
+	for _, w := range vb {
+		nb += float64(w * w)
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,115 @@
+package taggraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zachlatta/pin"
+)
+
+func post(tags ...string) *pin.Post {
+	return &pin.Post{Tags: tags}
+}
+
+var posts = []*pin.Post{
+	post("go", "code"),
+	post("Go", "code", "concurrency"),
+	post("golang", "code", "concurrency"),
+	post("databases", "sql"),
+	post("databases", "SQL", "sql"),
+	post("web-dev"),
+	post("webdev", "css"),
+	post("blog"),
+	post("blogs"),
+}
+
+func TestGraph(t *testing.T) {
+	g := Build(posts)
+
+	if g.Count("GO") != 2 || g.Count("sql") != 2 {
+		t.Errorf("Wrong counts %d %d", g.Count("go"), g.Count("sql"))
+	}
+
+	edges := g.Edges()
+	if len(edges) != 7 {
+		t.Fatalf("Expected 7 edges got %+v", edges)
+	}
+	if edges[0] != (Edge{"code", "concurrency", 2}) {
+		t.Errorf("Wrong heaviest edge %+v", edges[0])
+	}
+	if n := g.Neighbors("code"); len(n) != 3 || n[0].A != "code" || n[0].B != "concurrency" {
+		t.Errorf("Wrong neighbors %+v", n)
+	}
+
+	clusters := g.Clusters(1)
+	expected := [][]string{
+		{"code", "concurrency", "go", "golang"},
+		{"css", "webdev"},
+		{"databases", "sql"},
+	}
+	if !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("Wrong clusters %v", clusters)
+	}
+	if c := g.Clusters(2); !reflect.DeepEqual(c, [][]string{{"code", "concurrency", "go"}, {"databases", "sql"}}) {
+		t.Errorf("Wrong clusters for min weight 2 %v", c)
+	}
+
+	if o := g.Orphans(); !reflect.DeepEqual(o, []string{"blog", "blogs", "web-dev"}) {
+		t.Errorf("Wrong orphans %v", o)
+	}
+}
+
+func TestSynonyms(t *testing.T) {
+	syns := Build(posts).Synonyms(0.9)
+
+	var got []string
+	for _, s := range syns {
+		got = append(got, s.A+"="+s.B+" "+s.Reason)
+	}
+	expected := []string{"blog=blogs spelling", "web-dev=webdev spelling", "go=golang context"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Wrong synonyms %v", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	g := Build([]*pin.Post{post("a", `b"c`), post("a")})
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "graph tags {\n\t\"a\" [count=2];\n\t\"b\\\"c\" [count=1];\n\t\"a\" -- \"b\\\"c\" [weight=1];\n}\n"
+	if buf.String() != expected {
+		t.Errorf("Wrong DOT:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.WriteGraphML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 || doc.Graph.Edges[0].Target != `b"c` ||
+		doc.Graph.Nodes[0].Data.Value != 2 || !strings.Contains(buf.String(), `edgedefault="undirected"`) {
+		t.Errorf("Wrong GraphML:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out jsonGraph
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Nodes) != 2 || out.Edges[0] != (jsonEdge{"a", `b"c`, 1}) {
+		t.Errorf("Wrong JSON:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,194 @@
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// PolicyMode says what happens to posts whose tags break a TagPolicy.
+type PolicyMode int
+
+const (
+	// PolicyReject fails the request with a *TagPolicyError.
+	PolicyReject PolicyMode = iota
+
+	// PolicyRewrite drops disallowed tags and tags beyond the maximum, and
+	// sends the rest.
+	PolicyRewrite
+
+	// PolicyWarn logs the violations and sends the tags unchanged.
+	PolicyWarn
+)
+
+var policyModeNames = []string{"reject", "rewrite", "warn"}
+
+func (m PolicyMode) String() string {
+	if m >= 0 && int(m) < len(policyModeNames) {
+		return policyModeNames[m]
+	}
+	return fmt.Sprintf("PolicyMode(%d)", int(m))
+}
+
+// MarshalText encodes m as its name.
+func (m PolicyMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes a mode name.
+func (m *PolicyMode) UnmarshalText(text []byte) error {
+	for i, name := range policyModeNames {
+		if string(text) == name {
+			*m = PolicyMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown tag policy mode %q", text)
+}
+
+// TagPolicy is a controlled vocabulary for the tags of new posts. Tags are
+// compared case-insensitively. Aliases are replaced by the tag they stand
+// for in every mode except PolicyWarn, so they are never a violation. A
+// policy is loaded from JSON such as:
+//
+//	{
+//	  "allowed": ["go", "databases"],
+//	  "aliases": {"golang": "go"},
+//	  "patterns": ["project-[a-z]+"],
+//	  "max_tags": 5,
+//	  "mode": "reject"
+//	}
+type TagPolicy struct {
+	Allowed  []string          `json:"allowed"`
+	Aliases  map[string]string `json:"aliases"`  // alias to allowed tag
+	Patterns []string          `json:"patterns"` // regexps matching whole tags
+	MaxTags  int               `json:"max_tags"` // zero means no limit
+	Mode     PolicyMode        `json:"mode"`
+}
+
+// LoadTagPolicy reads a TagPolicy from the JSON file at path.
+func LoadTagPolicy(path string) (*TagPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p TagPolicy
+	if err := json.NewDecoder(f).Decode(&p); err != nil {
+		return nil, fmt.Errorf("tag policy %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// TagPolicyError lists the ways the tags of a post break the policy.
+type TagPolicyError struct {
+	Disallowed []string // tags neither allowed nor matching a pattern
+	Count      int      // the number of tags, if more than Max
+	Max        int
+}
+
+func (e *TagPolicyError) Error() string {
+	var parts []string
+	if len(e.Disallowed) > 0 {
+		parts = append(parts, "tags not allowed: "+strings.Join(e.Disallowed, ", "))
+	}
+	if e.Count > 0 {
+		parts = append(parts, fmt.Sprintf("%d tags, at most %d allowed", e.Count, e.Max))
+	}
+	return "pin: tag policy: " + strings.Join(parts, "; ")
+}
+
+// tagPolicy is a TagPolicy prepared for use by a Client.
+type tagPolicy struct {
+	allowed  map[string]bool
+	aliases  map[string]string
+	patterns []*regexp.Regexp
+	max      int
+	mode     PolicyMode
+}
+
+func compileTagPolicy(p *TagPolicy) (*tagPolicy, error) {
+	if p.Mode < PolicyReject || p.Mode > PolicyWarn {
+		return nil, fmt.Errorf("unknown tag policy mode %d", int(p.Mode))
+	}
+	if p.MaxTags < 0 {
+		return nil, fmt.Errorf("max tags must not be negative")
+	}
+
+	tp := &tagPolicy{
+		allowed: make(map[string]bool, len(p.Allowed)),
+		aliases: make(map[string]string, len(p.Aliases)),
+		max:     p.MaxTags,
+		mode:    p.Mode,
+	}
+	for _, t := range p.Allowed {
+		tp.allowed[strings.ToLower(t)] = true
+	}
+	for alias, t := range p.Aliases {
+		tp.aliases[strings.ToLower(alias)] = t
+	}
+	for _, s := range p.Patterns {
+		re, err := regexp.Compile(`(?i)^(?:` + s + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("tag policy pattern %q: %v", s, err)
+		}
+		tp.patterns = append(tp.patterns, re)
+	}
+	return tp, nil
+}
+
+func (p *tagPolicy) allows(tag string) bool {
+	if p.allowed[strings.ToLower(tag)] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply checks tags against the policy. It returns the tags to send and,
+// if the policy was broken, a *TagPolicyError. In PolicyReject mode the
+// request must not be sent when the error is not nil.
+func (p *tagPolicy) apply(tags []string) ([]string, error) {
+	var out, disallowed []string
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if alias, ok := p.aliases[strings.ToLower(t)]; ok && p.mode != PolicyWarn {
+			t = alias
+		}
+		if seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+
+		if !p.allows(t) {
+			disallowed = append(disallowed, t)
+			if p.mode == PolicyRewrite {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+
+	var tooMany int
+	if p.max > 0 && len(out) > p.max {
+		tooMany = len(out)
+		if p.mode == PolicyRewrite {
+			out = out[:p.max]
+		}
+	}
+
+	if p.mode == PolicyWarn {
+		out = tags
+	}
+	if len(disallowed) == 0 && tooMany == 0 {
+		return out, nil
+	}
+	return out, &TagPolicyError{Disallowed: disallowed, Count: tooMany, Max: p.max}
+}
@@ -0,0 +1,141 @@
+package pin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+var testPolicy = TagPolicy{
+	Allowed:  []string{"go", "databases"},
+	Aliases:  map[string]string{"golang": "go"},
+	Patterns: []string{"project-[a-z]+"},
+	MaxTags:  3,
+}
+
+// addWithPolicy adds a post with tags through a client using policy and
+// returns the tags that were sent, or nil if nothing was sent.
+func addWithPolicy(t *testing.T, policy TagPolicy, logger Logger, tags ...string) ([]string, error) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var sent []string
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		sent = strings.Fields(req.URL.Query().Get("tags"))
+		if sent == nil {
+			sent = []string{}
+		}
+		return httpmock.NewStringResponse(200, readFixture("ok")), nil
+	})
+
+	c, err := New(WithAuthToken(&token), WithTagPolicy(&policy), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Posts.Add("http://example.org", "Title", "", tags, nil, false, false, false)
+	return sent, err
+}
+
+func TestTagPolicyReject(t *testing.T) {
+	sent, err := addWithPolicy(t, testPolicy, nil, "Golang", "project-pin", "Databases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, []string{"go", "project-pin", "Databases"}) {
+		t.Errorf("Wrong tags sent %v", sent)
+	}
+
+	sent, err = addWithPolicy(t, testPolicy, nil, "go", "rust", "project-x", "databases")
+	perr, ok := err.(*TagPolicyError)
+	if !ok {
+		t.Fatalf("Expected *TagPolicyError got %v", err)
+	}
+	if sent != nil {
+		t.Error("Rejected post was sent")
+	}
+	if !reflect.DeepEqual(perr.Disallowed, []string{"rust"}) || perr.Count != 4 || perr.Max != 3 {
+		t.Errorf("Wrong error %+v", perr)
+	}
+}
+
+func TestTagPolicyRewrite(t *testing.T) {
+	policy := testPolicy
+	policy.Mode = PolicyRewrite
+
+	var logged bytes.Buffer
+	sent, err := addWithPolicy(t, policy, log.New(&logged, "", 0),
+		"rust", "golang", "go", "project-a", "project-b", "databases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, []string{"go", "project-a", "project-b"}) {
+		t.Errorf("Wrong tags sent %v", sent)
+	}
+	if !strings.Contains(logged.String(), "tags not allowed: rust") {
+		t.Errorf("Violation not logged: %q", logged.String())
+	}
+}
+
+func TestTagPolicyWarn(t *testing.T) {
+	policy := testPolicy
+	policy.Mode = PolicyWarn
+
+	var logged bytes.Buffer
+	sent, err := addWithPolicy(t, policy, log.New(&logged, "", 0), "golang", "rust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sent, []string{"golang", "rust"}) {
+		t.Errorf("Wrong tags sent %v", sent)
+	}
+	if !strings.Contains(logged.String(), "tags not allowed: golang, rust") {
+		t.Errorf("Violation not logged: %q", logged.String())
+	}
+}
+
+func TestLoadTagPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	data := `{"allowed": ["go"], "aliases": {"golang": "go"}, "patterns": ["x-.*"], "max_tags": 2, "mode": "rewrite"}`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadTagPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &TagPolicy{
+		Allowed:  []string{"go"},
+		Aliases:  map[string]string{"golang": "go"},
+		Patterns: []string{"x-.*"},
+		MaxTags:  2,
+		Mode:     PolicyRewrite,
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected %+v got %+v", expected, p)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"mode": "ignore"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTagPolicy(path); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+	if _, err := New(WithTagPolicy(&TagPolicy{Patterns: []string{"("}})); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,211 @@
+// Package tagtree treats namespaced tags such as "lang:go" and
+// "proj:billing/api" as a hierarchy, so that a whole branch can be counted,
+// listed or renamed at once.
+package tagtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zachlatta/pin"
+)
+
+// DefaultSeparators are the characters that split tags into levels when
+// a Parser has none set.
+const DefaultSeparators = ":/"
+
+// Parser splits tags into levels at any of its separator characters.
+type Parser struct {
+	Separators string
+}
+
+func (p Parser) separators() string {
+	if p.Separators == "" {
+		return DefaultSeparators
+	}
+	return p.Separators
+}
+
+// Split returns the levels of tag. Empty levels, as in "a::b", are dropped.
+func (p Parser) Split(tag string) []string {
+	return strings.FieldsFunc(tag, func(r rune) bool {
+		return strings.ContainsRune(p.separators(), r)
+	})
+}
+
+// prefixes returns the paths of tag and its ancestors, shortest first, each
+// as the start of tag up to a separator.
+func (p Parser) prefixes(tag string) []string {
+	var out []string
+	for i, r := range tag {
+		if i > 0 && strings.ContainsRune(p.separators(), r) &&
+			!strings.ContainsRune(p.separators(), rune(tag[i-1])) {
+			out = append(out, tag[:i])
+		}
+	}
+	return append(out, tag)
+}
+
+// Under reports whether tag is path or below it.
+func (p Parser) Under(tag, path string) bool {
+	if len(tag) < len(path) || !strings.EqualFold(tag[:len(path)], path) {
+		return false
+	}
+	return len(tag) == len(path) || strings.ContainsRune(p.separators(), rune(tag[len(path)]))
+}
+
+// Node is a level of the hierarchy.
+type Node struct {
+	Name     string // the last level, such as "api"
+	Path     string // the tag up to this level, such as "proj:billing/api"
+	IsTag    bool   // whether Path is itself a tag
+	Count    int    // the uses of the tag Path
+	Total    int    // the uses of Path and every tag below it
+	Parent   *Node
+	Children []*Node // sorted by name
+}
+
+// Walk calls fn for n and every node below it, depth first, stopping at the
+// first error.
+func (n *Node) Walk(fn func(*Node) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tags returns the tags at and below n in depth first order.
+func (n *Node) Tags() []string {
+	var tags []string
+	n.Walk(func(c *Node) error {
+		if c.IsTag {
+			tags = append(tags, c.Path)
+		}
+		return nil
+	})
+	return tags
+}
+
+// Tree is the hierarchy of a user's tags. Paths are compared
+// case-insensitively, as Pinboard does with tags.
+type Tree struct {
+	Root   *Node
+	parser Parser
+	byPath map[string]*Node
+}
+
+// Build returns the tree of tags, split by p.
+func Build(tags []*pin.Tag, p Parser) *Tree {
+	t := &Tree{
+		Root:   &Node{},
+		parser: p,
+		byPath: make(map[string]*Node),
+	}
+	for _, tag := range tags {
+		parent := t.Root
+		for _, path := range p.prefixes(tag.Name) {
+			n := t.byPath[strings.ToLower(path)]
+			if n == nil {
+				levels := p.Split(path)
+				n = &Node{Path: path, Parent: parent}
+				if len(levels) > 0 {
+					n.Name = levels[len(levels)-1]
+				}
+				t.byPath[strings.ToLower(path)] = n
+				parent.Children = append(parent.Children, n)
+			}
+			n.Total += tag.Count
+			parent = n
+		}
+		parent.Path = tag.Name // prefer the spelling of the tag itself
+		parent.IsTag = true
+		parent.Count += tag.Count
+		t.Root.Total += tag.Count
+	}
+
+	t.Root.Walk(func(n *Node) error {
+		sort.Slice(n.Children, func(i, j int) bool {
+			return strings.ToLower(n.Children[i].Name) < strings.ToLower(n.Children[j].Name)
+		})
+		return nil
+	})
+	return t
+}
+
+// Load builds the tree of the tags returned by tags.Get.
+func Load(tags pin.TagsAPI, p Parser) (*Tree, error) {
+	ts, _, err := tags.Get()
+	if err != nil {
+		return nil, err
+	}
+	return Build(ts, p), nil
+}
+
+// Find returns the node at path, or nil if no tag is at or below it.
+func (t *Tree) Find(path string) *Node {
+	return t.byPath[strings.ToLower(path)]
+}
+
+// Under returns the tags at and below path, such as every tag of a project
+// for "proj:billing".
+func (t *Tree) Under(path string) []string {
+	n := t.Find(path)
+	if n == nil {
+		return nil
+	}
+	return n.Tags()
+}
+
+// Rename moves the tags at and below from to to, keeping the rest of each
+// tag: with from "proj:billing" and to "proj:invoicing", "proj:billing/api"
+// becomes "proj:invoicing/api". Each tag is renamed with tags.Rename, which
+// folds it into the new tag if that exists already. It stops at the first
+// error and returns the number of tags renamed; tags that already have their
+// new name are skipped and not counted. The tree is not updated; Load it
+// again afterwards.
+func (t *Tree) Rename(tags pin.TagsAPI, from, to string) (int, error) {
+	if to == "" {
+		return 0, errors.New("tagtree: new path must not be empty")
+	}
+	old := t.Under(from)
+	if old == nil {
+		return 0, fmt.Errorf("tagtree: no tags under %s", from)
+	}
+
+	n := 0
+	for _, tag := range old {
+		newTag := to + tag[len(from):]
+		if newTag == tag {
+			continue
+		}
+		if _, err := tags.Rename(newTag, tag); err != nil {
+			return n, fmt.Errorf("tagtree: renaming %s to %s: %v", tag, newTag, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// WriteTo writes the tree to w as an indented outline with the total of each
+// branch.
+func (t *Tree) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	err := t.Root.Walk(func(n *Node) error {
+		if n == t.Root {
+			return nil
+		}
+		depth := len(t.parser.Split(n.Path)) - 1
+		c, err := fmt.Fprintf(w, "%s%s (%d)\n", strings.Repeat("  ", depth), n.Name, n.Total)
+		total += int64(c)
+		return err
+	})
+	return total, err
+}
@@ -0,0 +1,120 @@
+package tagtree
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/zachlatta/pin"
+)
+
+var tags = []*pin.Tag{
+	{Name: "lang:go", Count: 5},
+	{Name: "lang:rust", Count: 2},
+	{Name: "proj:billing", Count: 1},
+	{Name: "proj:billing/api", Count: 3},
+	{Name: "Proj:billing/web", Count: 4},
+	{Name: "proj:search", Count: 6},
+	{Name: "misc", Count: 7},
+}
+
+func TestBuild(t *testing.T) {
+	tree := Build(tags, Parser{})
+
+	if tree.Root.Total != 28 {
+		t.Errorf("Wrong total %d", tree.Root.Total)
+	}
+	billing := tree.Find("PROJ:BILLING")
+	if billing == nil || billing.Count != 1 || billing.Total != 8 || !billing.IsTag || billing.Name != "billing" {
+		t.Fatalf("Wrong node %+v", billing)
+	}
+	if proj := tree.Find("proj"); proj.IsTag || proj.Total != 14 || len(proj.Children) != 2 {
+		t.Errorf("Wrong node %+v", proj)
+	}
+	if billing.Parent != tree.Find("proj") {
+		t.Error("Wrong parent")
+	}
+
+	expected := []string{"proj:billing", "proj:billing/api", "Proj:billing/web"}
+	if got := tree.Under("proj:billing"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Wrong tags under proj:billing %v", got)
+	}
+	if got := tree.Under("nothing"); got != nil {
+		t.Errorf("Expected no tags got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := tree.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	outline := "lang (7)\n  go (5)\n  rust (2)\nmisc (7)\nproj (14)\n  billing (8)\n    api (3)\n    web (4)\n  search (6)\n"
+	if buf.String() != outline {
+		t.Errorf("Wrong outline:\n%s", buf.String())
+	}
+}
+
+func TestParser(t *testing.T) {
+	p := Parser{Separators: "."}
+	if got := p.Split("a.b..c"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Wrong levels %v", got)
+	}
+	if !p.Under("a.b.c", "A.b") || p.Under("a.bc", "a.b") || !p.Under("a.b", "a.b") {
+		t.Error("Wrong result from Under")
+	}
+
+	tree := Build([]*pin.Tag{{Name: "a..b", Count: 1}}, p)
+	if n := tree.Find("a.."); n != nil {
+		t.Errorf("Unexpected node for empty level %+v", n)
+	}
+	if n := tree.Find("a"); n == nil || len(n.Children) != 1 || n.Children[0].Name != "b" {
+		t.Errorf("Wrong node %+v", n)
+	}
+}
+
+func TestRename(t *testing.T) {
+	fake := &pin.FakeTagsService{
+		GetStub: func() ([]*pin.Tag, *http.Response, error) {
+			return tags, nil, nil
+		},
+	}
+	tree, err := Load(fake, Parser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := tree.Rename(fake, "proj:billing", "proj:invoicing")
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 renames got %d, %v", n, err)
+	}
+	expected := []pin.FakeTagsRenameCall{
+		{NewTag: "proj:invoicing", OldTag: "proj:billing"},
+		{NewTag: "proj:invoicing/api", OldTag: "proj:billing/api"},
+		{NewTag: "proj:invoicing/web", OldTag: "Proj:billing/web"},
+	}
+	if got := fake.RenameCalls(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Wrong renames %+v", got)
+	}
+
+	if _, err := tree.Rename(fake, "nothing", "x"); err == nil {
+		t.Error("Expected error for unknown path")
+	}
+
+	// Only the tag whose case differs actually changes.
+	if n, err := tree.Rename(&pin.FakeTagsService{}, "proj:billing", "proj:billing"); err != nil || n != 1 {
+		t.Errorf("Expected 1 rename got %d, %v", n, err)
+	}
+
+	calls := 0
+	fake.RenameStub = func(string, string) (*http.Response, error) {
+		calls++
+		if calls > 1 {
+			return nil, errors.New("boom")
+		}
+		return nil, nil
+	}
+	if n, err := tree.Rename(fake, "proj:billing", "proj:invoicing"); err == nil || n != 1 {
+		t.Errorf("Expected failure on second rename got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,47 @@
+package watch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/zachlatta/pin/internal/atomicfile"
+)
+
+// Store persists a Watcher's cursor.
+type Store interface {
+	// Load returns the saved cursor, or nil if there is none yet.
+	Load() (*Cursor, error)
+	Save(c *Cursor) error
+}
+
+// FileStore keeps the cursor in a JSON file.
+type FileStore struct {
+	Path string
+}
+
+func (s *FileStore) Load() (*Cursor, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save replaces the cursor file atomically, so a crash never leaves a
+// partial cursor behind.
+func (s *FileStore) Save(c *Cursor) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0644)
+}
@@ -0,0 +1,287 @@
+// Package watch turns changes to a Pinboard account into a stream of events.
+// It polls PostsService.LastTimeUpdated and only fetches bookmarks when the
+// account has changed since the last check.
+package watch
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+// DefaultInterval is the polling interval used when none is set. posts/all
+// may only be called once every five minutes.
+const DefaultInterval = 5 * time.Minute
+
+// EventType describes what happened to a bookmark.
+type EventType int
+
+const (
+	Added EventType = iota
+	Updated
+	Deleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Deleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// Event reports a change to a single bookmark. For Deleted events only the
+// URL of Post is known.
+type Event struct {
+	Type EventType
+	Post *pin.Post
+}
+
+// Cursor is the state a Watcher keeps between polls: when the account last
+// changed and a fingerprint of every bookmark seen at that time, by URL.
+type Cursor struct {
+	Updated time.Time         `json:"updated"`
+	Posts   map[string]string `json:"posts"`
+}
+
+// Watcher polls an account for changes. A Watcher must not be used from more
+// than one goroutine at a time.
+type Watcher struct {
+	Posts pin.PostsAPI
+
+	// Store persists the cursor so that a restarted Watcher carries on where
+	// it stopped. If nil, the cursor is only kept in memory.
+	Store Store
+
+	// Interval is the time between polls. Zero means DefaultInterval.
+	Interval time.Duration
+
+	// Recent, if positive, makes the Watcher fetch only that many of the
+	// most recent bookmarks instead of all of them. This is cheaper but
+	// cannot detect deletions or changes to older bookmarks.
+	Recent int
+
+	// EmitInitial reports every bookmark as Added on the first poll without
+	// a stored cursor. Otherwise the first poll only records the current
+	// state.
+	EmitInitial bool
+
+	// OnError is called with errors from polls made by Run, which carries
+	// on polling afterwards. If nil, Run returns the first error instead.
+	OnError func(error)
+
+	cursor *Cursor
+}
+
+// Poll checks the account once and returns the changes since the previous
+// poll. The new cursor is saved before Poll returns.
+func (w *Watcher) Poll() ([]Event, error) {
+	events, next, err := w.check()
+	if err != nil || next == nil {
+		return events, err
+	}
+	return events, w.commit(next)
+}
+
+// Run polls the account every Interval until ctx is done, sending each
+// change to events. The cursor is saved only once all the changes of a poll
+// have been delivered, so a restart never loses events, although it may
+// repeat some.
+func (w *Watcher) Run(ctx context.Context, events chan<- Event) error {
+	return w.RunFunc(ctx, func(e Event) error {
+		select {
+		case events <- e:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// RunFunc is like Run but calls fn for each change. If fn returns an error,
+// the cursor is not advanced and the changes are reported again on the next
+// poll.
+func (w *Watcher) RunFunc(ctx context.Context, fn func(Event) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	for {
+		if err := w.runOnce(fn); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError == nil {
+				return err
+			}
+			w.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *Watcher) runOnce(fn func(Event) error) error {
+	events, next, err := w.check()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	return w.commit(next)
+}
+
+// check compares the account against the cursor. It returns a nil cursor if
+// nothing changed.
+func (w *Watcher) check() ([]Event, *Cursor, error) {
+	if w.Posts == nil {
+		return nil, nil, errors.New("watch: no posts service")
+	}
+	prev, err := w.load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updated, _, err := w.Posts.LastTimeUpdated()
+	if err != nil {
+		return nil, nil, err
+	}
+	if updated == nil {
+		return nil, nil, errors.New("watch: no last update time")
+	}
+	if prev != nil && !updated.After(prev.Updated) {
+		return nil, nil, nil
+	}
+
+	var posts []*pin.Post
+	if w.Recent > 0 {
+		posts, _, err = w.Posts.Recent(nil, w.Recent)
+	} else {
+		posts, _, err = w.Posts.All(nil, 0, 0, nil, nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := &Cursor{Updated: *updated, Posts: make(map[string]string, len(posts))}
+	if w.Recent > 0 && prev != nil {
+		// Bookmarks outside the recent window are unchanged as far as we
+		// know, so keep them.
+		for u, fp := range prev.Posts {
+			next.Posts[u] = fp
+		}
+	}
+	for _, p := range posts {
+		next.Posts[p.URL] = Fingerprint(p)
+	}
+
+	if prev == nil && !w.EmitInitial {
+		return nil, next, nil
+	}
+	var old map[string]string
+	if prev != nil {
+		old = prev.Posts
+	}
+	return diff(old, posts, w.Recent <= 0), next, nil
+}
+
+// diff lists the changes between the fingerprints in old and posts. Deleted
+// events are only produced when posts is the complete set of bookmarks.
+func diff(old map[string]string, posts []*pin.Post, complete bool) []Event {
+	var events []Event
+	seen := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		seen[p.URL] = true
+		fp, ok := old[p.URL]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: Added, Post: p})
+		case fp != Fingerprint(p):
+			events = append(events, Event{Type: Updated, Post: p})
+		}
+	}
+
+	if complete {
+		var deleted []string
+		for u := range old {
+			if !seen[u] {
+				deleted = append(deleted, u)
+			}
+		}
+		sort.Strings(deleted)
+		for _, u := range deleted {
+			events = append(events, Event{Type: Deleted, Post: &pin.Post{URL: u}})
+		}
+	}
+	return events
+}
+
+func (w *Watcher) load() (*Cursor, error) {
+	if w.cursor != nil || w.Store == nil {
+		return w.cursor, nil
+	}
+	c, err := w.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	w.cursor = c
+	return c, nil
+}
+
+func (w *Watcher) commit(c *Cursor) error {
+	if w.Store != nil {
+		if err := w.Store.Save(c); err != nil {
+			return err
+		}
+	}
+	w.cursor = c
+	return nil
+}
+
+// Fingerprint summarises the user-editable fields of p. It changes whenever
+// the bookmark is edited.
+func Fingerprint(p *pin.Post) string {
+	tags := append([]string(nil), p.Tags...)
+	sort.Strings(tags)
+
+	var ts string
+	if p.Time != nil {
+		ts = p.Time.UTC().Format(time.RFC3339)
+	}
+
+	h := sha1.New()
+	for _, f := range []string{p.URL, p.Title, p.Description, strings.Join(tags, " "),
+		ts, boolString(p.ToRead), boolString(p.Shared)} {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func boolString(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,172 @@
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+)
+
+var (
+	time1 = time.Date(2011, time.March, 24, 19, 2, 7, 0, time.UTC)
+	time2 = time1.Add(time.Hour)
+)
+
+// account is a fake Pinboard account whose posts and update time can be
+// changed between polls.
+type account struct {
+	updated time.Time
+	posts   []*pin.Post
+}
+
+func (a *account) fake() *pin.FakePostsService {
+	return &pin.FakePostsService{
+		LastTimeUpdatedStub: func() (*time.Time, *http.Response, error) {
+			t := a.updated
+			return &t, nil, nil
+		},
+		AllStub: func([]string, int, int, *time.Time, *time.Time) ([]*pin.Post, *http.Response, error) {
+			return a.posts, nil, nil
+		},
+		RecentStub: func(tags []string, count int) ([]*pin.Post, *http.Response, error) {
+			if count < len(a.posts) {
+				return a.posts[:count], nil, nil
+			}
+			return a.posts, nil, nil
+		},
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	acct := &account{updated: time1, posts: []*pin.Post{
+		{URL: "http://a.example", Title: "A", Tags: []string{"one"}},
+		{URL: "http://b.example", Title: "B"},
+	}}
+	posts := acct.fake()
+	w := &Watcher{Posts: posts}
+
+	events, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events on first poll got %d", len(events))
+	}
+
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 || len(posts.AllCalls()) != 1 {
+		t.Errorf("Fetched posts although nothing changed (%d events, %d fetches)",
+			len(events), len(posts.AllCalls()))
+	}
+
+	acct.updated = time2
+	acct.posts = []*pin.Post{
+		{URL: "http://a.example", Title: "A", Tags: []string{"one", "two"}},
+		{URL: "http://c.example", Title: "C"},
+	}
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ EventType
+		url string
+	}{
+		{Updated, "http://a.example"},
+		{Added, "http://c.example"},
+		{Deleted, "http://b.example"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Wrong number of events expected %d got %d: %v", len(want), len(events), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i].typ || e.Post.URL != want[i].url {
+			t.Errorf("Event %d: expected %s %s got %s %s", i, want[i].typ, want[i].url,
+				e.Type, e.Post.URL)
+		}
+	}
+}
+
+func TestWatcherRecent(t *testing.T) {
+	acct := &account{updated: time1, posts: []*pin.Post{
+		{URL: "http://a.example"},
+		{URL: "http://b.example"},
+	}}
+	posts := acct.fake()
+	w := &Watcher{Posts: posts, Recent: 1, EmitInitial: true}
+
+	events, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != Added || events[0].Post.URL != "http://a.example" {
+		t.Errorf("Wrong initial events %v", events)
+	}
+
+	acct.updated = time2
+	acct.posts = []*pin.Post{{URL: "http://c.example"}}
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != Added {
+		t.Errorf("Expected only an added event got %v", events)
+	}
+	if len(posts.AllCalls()) != 0 {
+		t.Error("Fetched all posts in recent mode")
+	}
+}
+
+func TestWatcherFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &FileStore{Path: filepath.Join(dir, "cursor.json")}
+
+	acct := &account{updated: time1, posts: []*pin.Post{{URL: "http://a.example"}}}
+	w := &Watcher{Posts: acct.fake(), Store: store, EmitInitial: true}
+	if events, err := w.Poll(); err != nil || len(events) != 1 {
+		t.Fatalf("Expected one event got %v (%v)", events, err)
+	}
+
+	// A restarted watcher picks up the saved cursor and re-emits nothing.
+	acct.updated = time2
+	w = &Watcher{Posts: acct.fake(), Store: store, EmitInitial: true}
+	events, err := w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Restarted watcher re-emitted %v", events)
+	}
+}
+
+func TestWatcherRunFunc(t *testing.T) {
+	acct := &account{updated: time1, posts: []*pin.Post{{URL: "http://a.example"}}}
+	w := &Watcher{Posts: acct.fake(), Interval: time.Millisecond, EmitInitial: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []Event
+	err := w.RunFunc(ctx, func(e Event) error {
+		got = append(got, e)
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+	if len(got) != 1 || got[0].Post.URL != "http://a.example" {
+		t.Errorf("Wrong events delivered %v", got)
+	}
+}
@@ -0,0 +1,261 @@
+// Package webhook delivers bookmark changes to HTTP endpoints. It is meant to
+// be fed by the watch package, so that other services can react to new
+// bookmarks without polling Pinboard themselves.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/watch"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body,
+// keyed with the target's secret and prefixed with "sha256=".
+const SignatureHeader = "X-Pin-Signature"
+
+// EventHeader carries the type of the event being delivered.
+const EventHeader = "X-Pin-Event"
+
+// Target is an endpoint that receives events.
+type Target struct {
+	URL string
+
+	// Secret signs the payload. If empty, requests are not signed.
+	Secret string
+
+	// Tags limits delivery to bookmarks with at least one of these tags,
+	// compared without regard to case. If empty, every event is delivered.
+	Tags []string
+}
+
+func (t *Target) matches(p *pin.Post) bool {
+	if len(t.Tags) == 0 {
+		return true
+	}
+	for _, want := range t.Tags {
+		for _, tag := range p.Tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body posted to targets.
+type Payload struct {
+	Event       string     `json:"event"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	ToRead      bool       `json:"toread,omitempty"`
+	Shared      bool       `json:"shared,omitempty"`
+	Time        *time.Time `json:"time,omitempty"`
+	Delivered   time.Time  `json:"delivered"`
+}
+
+func newPayload(e watch.Event, now time.Time) *Payload {
+	p := &Payload{
+		Event:       e.Type.String(),
+		URL:         e.Post.URL,
+		Title:       e.Post.Title,
+		Description: e.Post.Description,
+		Tags:        e.Post.Tags,
+		ToRead:      e.Post.ToRead,
+		Shared:      e.Post.Shared,
+		Delivered:   now.UTC(),
+	}
+	if e.Post.Time != nil {
+		t := e.Post.Time.UTC()
+		p.Time = &t
+	}
+	return p
+}
+
+// Sign returns the value of SignatureHeader for body signed with secret.
+// Receivers can compare it against the header with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher posts events to a set of targets.
+type Dispatcher struct {
+	Targets []Target
+
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// MaxRetries is the number of times a failed delivery is retried.
+	MaxRetries int
+
+	// Backoff is the delay before the first retry. It doubles after every
+	// attempt. Zero means one second.
+	Backoff time.Duration
+
+	// DeadLetter is the path of a file that deliveries which failed every
+	// attempt are appended to, one JSON object per line. If empty, they are
+	// dropped once reported.
+	DeadLetter string
+
+	// OnError is called by the function returned from Handle when a
+	// delivery fails.
+	OnError func(error)
+
+	mu sync.Mutex // serialises writes to DeadLetter
+}
+
+// DeadLetter is a delivery that failed every attempt.
+type DeadLetter struct {
+	Target  string   `json:"target"`
+	Error   string   `json:"error"`
+	Payload *Payload `json:"payload"`
+}
+
+// Dispatch delivers e to every target whose filter matches, concurrently.
+// Deliveries that still fail after all retries are written to the dead-letter
+// file and reported in the returned error.
+func (d *Dispatcher) Dispatch(ctx context.Context, e watch.Event) error {
+	payload := newPayload(e, time.Now())
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(d.Targets))
+	matched := 0
+	for i := range d.Targets {
+		t := &d.Targets[i]
+		// The tags of a deleted bookmark are unknown, so deletions go to
+		// every target.
+		if e.Type != watch.Deleted && !t.matches(e.Post) {
+			continue
+		}
+		matched++
+		wg.Add(1)
+		go func(i int, t *Target) {
+			defer wg.Done()
+			err := d.deliver(ctx, t, payload.Event, body)
+			if err != nil {
+				errs[i] = err
+				if dlErr := d.deadLetter(t, payload, err); dlErr != nil {
+					errs[i] = fmt.Errorf("%v (dead letter: %v)", err, dlErr)
+				}
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", d.Targets[i].URL, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("webhook: %d of %d deliveries failed: %v", len(failed),
+			matched, failed)
+	}
+	return nil
+}
+
+// Handle returns a function suitable for watch.Watcher.RunFunc. Failed
+// deliveries are passed to OnError and the dead-letter file rather than
+// stopping the watcher.
+func (d *Dispatcher) Handle(ctx context.Context) func(watch.Event) error {
+	return func(e watch.Event) error {
+		if err := d.Dispatch(ctx, e); err != nil && d.OnError != nil {
+			d.OnError(err)
+		}
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, t *Target, event string, body []byte) error {
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = d.post(ctx, t, event, body)
+		if err == nil || attempt >= d.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff << uint(attempt)):
+		}
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, t *Target, event string, body []byte) error {
+	req, err := http.NewRequest("POST", t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	if t.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(t.Secret, body))
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.CopyN(ioutil.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deadLetter(t *Target, payload *Payload, cause error) error {
+	if d.DeadLetter == "" {
+		return nil
+	}
+	line, err := json.Marshal(&DeadLetter{Target: t.URL, Error: cause.Error(), Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := os.OpenFile(d.DeadLetter, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
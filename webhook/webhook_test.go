@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zachlatta/pin"
+	"github.com/zachlatta/pin/watch"
+)
+
+// receiver is a local webhook endpoint that records what it was sent and
+// fails the first failures requests.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+var event = watch.Event{Type: watch.Added, Post: &pin.Post{
+	URL:   "https://golang.org/",
+	Title: "The Go Programming Language",
+	Tags:  []string{"golang", "programming"},
+}}
+
+func TestDispatchSigned(t *testing.T) {
+	recv := &receiver{}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	d := &Dispatcher{Targets: []Target{{URL: srv.URL, Secret: "s3cret"}}}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recv.requests) != 1 {
+		t.Fatalf("Expected 1 request got %d", len(recv.requests))
+	}
+	req, body := recv.requests[0], recv.bodies[0]
+	if req.Method != "POST" || req.Header.Get(EventHeader) != "added" {
+		t.Errorf("Wrong request %s %s", req.Method, req.Header.Get(EventHeader))
+	}
+	if sig := req.Header.Get(SignatureHeader); !hmac.Equal([]byte(sig), []byte(Sign("s3cret", body))) {
+		t.Errorf("Wrong signature %s", sig)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != "added" || p.URL != "https://golang.org/" || len(p.Tags) != 2 {
+		t.Errorf("Wrong payload %+v", p)
+	}
+}
+
+func TestDispatchTagFilter(t *testing.T) {
+	wanted, ignored := &receiver{}, &receiver{}
+	srvWanted := httptest.NewServer(wanted)
+	defer srvWanted.Close()
+	srvIgnored := httptest.NewServer(ignored)
+	defer srvIgnored.Close()
+
+	d := &Dispatcher{Targets: []Target{
+		{URL: srvWanted.URL, Tags: []string{"GoLang"}},
+		{URL: srvIgnored.URL, Tags: []string{"cooking"}},
+	}}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(wanted.requests) != 1 || len(ignored.requests) != 0 {
+		t.Errorf("Filter not applied: %d and %d requests", len(wanted.requests),
+			len(ignored.requests))
+	}
+}
+
+func TestDispatchFailureCount(t *testing.T) {
+	recv := &receiver{failures: 10}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	// Only the first target matches, so only it counts.
+	d := &Dispatcher{Targets: []Target{
+		{URL: srv.URL},
+		{URL: srv.URL, Tags: []string{"cooking"}},
+	}}
+	err := d.Dispatch(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 deliveries failed") {
+		t.Errorf("Wrong error %v", err)
+	}
+}
+
+func TestDispatchRetry(t *testing.T) {
+	recv := &receiver{failures: 2}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	d := &Dispatcher{
+		Targets:    []Target{{URL: srv.URL}},
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+	}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(recv.requests) != 3 {
+		t.Errorf("Expected 3 attempts got %d", len(recv.requests))
+	}
+}
+
+func TestDispatchDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recv := &receiver{failures: 10}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	d := &Dispatcher{
+		Targets:    []Target{{URL: srv.URL}},
+		MaxRetries: 1,
+		Backoff:    time.Millisecond,
+		DeadLetter: filepath.Join(dir, "dead.jsonl"),
+	}
+	if err := d.Dispatch(context.Background(), event); err == nil {
+		t.Error("Expected delivery error")
+	}
+
+	f, err := os.Open(d.DeadLetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, dl)
+	}
+	if len(letters) != 1 || letters[0].Target != srv.URL || letters[0].Payload.URL != "https://golang.org/" {
+		t.Errorf("Wrong dead letters %+v", letters)
+	}
+}